@@ -0,0 +1,350 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+type backupConfig struct {
+    Dir         string `toml:"dir"`
+    Schedule    string `toml:"schedule"`
+    KeepDaily   int    `toml:"keep_daily"`
+    KeepWeekly  int    `toml:"keep_weekly"`
+    KeepMonthly int    `toml:"keep_monthly"`
+}
+
+func (c backupConfig) validate() error {
+    switch c.Schedule {
+    case "daily", "startup", "off":
+    default:
+        return fmt.Errorf("schedule: want daily, startup or off, got %q", c.Schedule)
+    }
+    if c.Dir == "" {
+        return fmt.Errorf("dir: must not be empty")
+    }
+    if c.KeepDaily < 1 || c.KeepWeekly < 0 || c.KeepMonthly < 0 {
+        return fmt.Errorf("keep_daily must be at least 1 and keep_weekly and keep_monthly not negative")
+    }
+    return nil
+}
+
+const backupStamp = "20060102-150405"
+
+// Backups are named prefix-stamp.db. Only those with backupPrefix are listed
+// and pruned, so the copy restore saves first is kept until removed by hand.
+const (
+    backupPrefix  = "scheduler-"
+    restorePrefix = "pre-restore-"
+)
+
+type backupFile struct {
+    path  string
+    taken time.Time
+    seq   int
+}
+
+// parseBackupName reads the time a backup was taken from its name. Backups
+// taken within the same second get a -2, -3, ... suffix, returned as seq.
+func parseBackupName(name string) (time.Time, int, bool) {
+    stamp, ok := strings.CutPrefix(name, backupPrefix)
+    if !ok || !strings.HasSuffix(stamp, ".db") {
+        return time.Time{}, 0, false
+    }
+    stamp = strings.TrimSuffix(stamp, ".db")
+    seq := 1
+    if len(stamp) > len(backupStamp) {
+        n, err := strconv.Atoi(strings.TrimPrefix(stamp[len(backupStamp):], "-"))
+        if err != nil || stamp[len(backupStamp)] != '-' || n < 2 {
+            return time.Time{}, 0, false
+        }
+        seq = n
+        stamp = stamp[:len(backupStamp)]
+    }
+    taken, err := time.ParseInLocation(backupStamp, stamp, time.Local)
+    if err != nil {
+        return time.Time{}, 0, false
+    }
+    return taken, seq, true
+}
+
+// listBackups returns the backups in dir, newest first.
+func listBackups(dir string) ([]backupFile, error) {
+    entries, err := os.ReadDir(dir)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    var backups []backupFile
+    for _, e := range entries {
+        taken, seq, ok := parseBackupName(e.Name())
+        if !ok {
+            continue
+        }
+        backups = append(backups, backupFile{path: filepath.Join(dir, e.Name()), taken: taken, seq: seq})
+    }
+    sort.Slice(backups, func(i, j int) bool {
+        if !backups[i].taken.Equal(backups[j].taken) {
+            return backups[i].taken.After(backups[j].taken)
+        }
+        return backups[i].seq > backups[j].seq
+    })
+    return backups, nil
+}
+
+// makeBackup snapshots the database into the backup directory under a name
+// starting with prefix and checks the copy.
+func makeBackup(database *db.DB, c backupConfig, prefix string) (string, error) {
+    if err := os.MkdirAll(c.Dir, 0700); err != nil {
+        return "", fmt.Errorf("failed to create backup directory: %v", err)
+    }
+
+    stamp := time.Now().Format(backupStamp)
+    tmp := filepath.Join(c.Dir, prefix+stamp+".db.tmp")
+    os.Remove(tmp)
+    if err := database.Backup(tmp); err != nil {
+        os.Remove(tmp)
+        return "", fmt.Errorf("backup failed: %v", err)
+    }
+    if err := db.CheckIntegrity(tmp); err != nil {
+        os.Remove(tmp)
+        return "", err
+    }
+
+    // Never replace a backup taken earlier in the same second.
+    path := filepath.Join(c.Dir, prefix+stamp+".db")
+    for seq := 2; ; seq++ {
+        if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+            break
+        }
+        path = filepath.Join(c.Dir, fmt.Sprintf("%s%s-%d.db", prefix, stamp, seq))
+    }
+    if err := os.Rename(tmp, path); err != nil {
+        os.Remove(tmp)
+        return "", err
+    }
+    return path, nil
+}
+
+// pruneBackups keeps the newest backup of each of the last keep_daily days,
+// keep_weekly weeks and keep_monthly months that have one, and deletes the
+// rest.
+func pruneBackups(c backupConfig) error {
+    backups, err := listBackups(c.Dir)
+    if err != nil {
+        return err
+    }
+
+    keep := make(map[string]bool)
+    rules := []struct {
+        n      int
+        period func(time.Time) string
+    }{
+        {c.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+        {c.KeepWeekly, func(t time.Time) string {
+            year, week := t.ISOWeek()
+            return fmt.Sprintf("%d-W%02d", year, week)
+        }},
+        {c.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+    }
+    for _, rule := range rules {
+        seen := make(map[string]bool)
+        for _, b := range backups {
+            if len(seen) == rule.n {
+                break
+            }
+            if p := rule.period(b.taken); !seen[p] {
+                seen[p] = true
+                keep[b.path] = true
+            }
+        }
+    }
+
+    for _, b := range backups {
+        if keep[b.path] {
+            continue
+        }
+        if err := os.Remove(b.path); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// autoBackup takes the scheduled backup if one is due: always at startup for
+// schedule "startup", once a day for "daily". It returns the new backup's
+// path, or "" when none was due.
+func autoBackup(database *db.DB, c backupConfig, startup bool) (string, error) {
+    switch c.Schedule {
+    case "off":
+        return "", nil
+    case "startup":
+        if !startup {
+            return "", nil
+        }
+    case "daily":
+        backups, err := listBackups(c.Dir)
+        if err != nil {
+            return "", err
+        }
+        if len(backups) > 0 && sameDay(backups[0].taken, time.Now()) {
+            return "", nil
+        }
+    }
+    path, err := makeBackup(database, c, backupPrefix)
+    if err != nil {
+        return "", err
+    }
+    return path, pruneBackups(c)
+}
+
+type backupMsg struct {
+    path string
+    err  error
+}
+
+// startupBackup takes the scheduled startup backup off the UI thread, since
+// it can wait for another process to release the database. It opens its own
+// connection so the TUI's queries are not queued behind it.
+func startupBackup(s settings) tea.Cmd {
+    return func() tea.Msg {
+        database, err := db.Open(s.dbPath)
+        if err != nil {
+            return backupMsg{err: err}
+        }
+        defer database.Close()
+        path, err := autoBackup(database, s.backup, true)
+        return backupMsg{path: path, err: err}
+    }
+}
+
+func runBackup(args []string) error {
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+
+    if len(args) == 1 && args[0] == "list" {
+        backups, err := listBackups(s.backup.Dir)
+        if err != nil {
+            return err
+        }
+        if len(backups) == 0 {
+            fmt.Printf("no backups in %s\n", s.backup.Dir)
+        }
+        for _, b := range backups {
+            fmt.Printf("%s  %s\n", b.taken.Format("2006-01-02 15:04:05"), b.path)
+        }
+        return nil
+    }
+    if len(args) > 0 {
+        return errors.New("usage: scheduler backup [list]")
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    path, err := makeBackup(database, s.backup, backupPrefix)
+    if err != nil {
+        return err
+    }
+    fmt.Printf("backed up to %s\n", path)
+    return pruneBackups(s.backup)
+}
+
+// runRestore checks a backup and copies it over the database, saving the
+// current contents first in case the wrong file was picked. That copy gets
+// restorePrefix so pruning never removes it.
+func runRestore(args []string) error {
+    if len(args) != 1 {
+        return errors.New("usage: scheduler restore <backup file>")
+    }
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+
+    file := expandHome(args[0])
+    if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) && !strings.ContainsRune(file, os.PathSeparator) {
+        file = filepath.Join(s.backup.Dir, file)
+    }
+    if err := db.CheckIntegrity(file); err != nil {
+        return fmt.Errorf("not restoring: %v", err)
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        // Too damaged to open, so nothing can be reading it through SQLite
+        // either; put the backup in its place on disk.
+        return replaceDatabase(s.dbPath, file, err)
+    }
+    defer database.Close()
+
+    saved, err := makeBackup(database, s.backup, restorePrefix)
+    if err != nil {
+        return fmt.Errorf("failed to save the current database first: %v", err)
+    }
+    if err := database.Restore(file); err != nil {
+        return fmt.Errorf("restore failed: %v", err)
+    }
+    if err := db.CheckIntegrity(s.dbPath); err != nil {
+        return fmt.Errorf("restore failed, the previous database is in %s: %v", saved, err)
+    }
+
+    // Bring a backup taken by an older version up to the current schema.
+    database.Close()
+    migrated, err := db.Open(s.dbPath)
+    if err != nil {
+        return err
+    }
+    migrated.Close()
+    fmt.Printf("restored %s; the previous database was saved to %s\n", file, saved)
+    return nil
+}
+
+func replaceDatabase(dbPath, file string, openErr error) error {
+    aside := dbPath + ".broken-" + time.Now().Format(backupStamp)
+    if err := os.Rename(dbPath, aside); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return fmt.Errorf("%v; moving it aside also failed: %v", openErr, err)
+    }
+    os.Remove(dbPath + "-journal")
+
+    src, err := os.Open(file)
+    if err != nil {
+        return err
+    }
+    defer src.Close()
+    tmp := dbPath + ".restore"
+    dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(dst, src); err != nil {
+        dst.Close()
+        return err
+    }
+    if err := dst.Close(); err != nil {
+        return err
+    }
+    if err := os.Rename(tmp, dbPath); err != nil {
+        return err
+    }
+    fmt.Printf("restored %s; the database that failed to open (%v) was moved to %s\n", file, openErr, aside)
+    return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+func TestMakeBackupKeepsSameSecondBackups(t *testing.T) {
+    database := openTestDB(t)
+    c := backupConfig{Dir: filepath.Join(t.TempDir(), "backups"), Schedule: "off", KeepDaily: 7}
+
+    seen := make(map[string]bool)
+    for i := 0; i < 3; i++ {
+        path, err := makeBackup(database, c, backupPrefix)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if seen[path] {
+            t.Fatalf("backup %d overwrote %s", i+1, path)
+        }
+        seen[path] = true
+    }
+
+    backups, err := listBackups(c.Dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(backups) != 3 {
+        t.Fatalf("listed %d backups, want 3", len(backups))
+    }
+    for i := 1; i < len(backups); i++ {
+        if backups[i].taken.After(backups[i-1].taken) ||
+            (backups[i].taken.Equal(backups[i-1].taken) && backups[i].seq > backups[i-1].seq) {
+            t.Errorf("backups not newest first: %v before %v", backups[i-1].path, backups[i].path)
+        }
+    }
+}
+
+func TestPruneKeepsPreRestoreBackup(t *testing.T) {
+    database := openTestDB(t)
+    c := backupConfig{Dir: filepath.Join(t.TempDir(), "backups"), Schedule: "off", KeepDaily: 1}
+
+    saved, err := makeBackup(database, c, restorePrefix)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 2; i++ {
+        if _, err := makeBackup(database, c, backupPrefix); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := pruneBackups(c); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := os.Stat(saved); err != nil {
+        t.Errorf("pruning removed the pre-restore backup: %v", err)
+    }
+    backups, err := listBackups(c.Dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(backups) != 1 {
+        t.Errorf("%d backups after pruning to one a day, want 1", len(backups))
+    }
+}
+
+func TestParseBackupName(t *testing.T) {
+    tests := []struct {
+        name string
+        seq  int
+        ok   bool
+    }{
+        {"scheduler-20261018-093000.db", 1, true},
+        {"scheduler-20261018-093000-2.db", 2, true},
+        {"scheduler-20261018-093000-12.db", 12, true},
+        {"scheduler-20261018-093000-1.db", 0, false},
+        {"scheduler-20261018-093000x2.db", 0, false},
+        {"scheduler-20261018-093000.db.tmp", 0, false},
+        {"scheduler-2026.db", 0, false},
+        {"notes.db", 0, false},
+    }
+    for _, tt := range tests {
+        _, seq, ok := parseBackupName(tt.name)
+        if ok != tt.ok || seq != tt.seq {
+            t.Errorf("parseBackupName(%q) = %d, %v; want %d, %v", tt.name, seq, ok, tt.seq, tt.ok)
+        }
+    }
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/xml"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/url"
+    "os/exec"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+type caldavConfig struct {
+    URL             string `toml:"url"`
+    Username        string `toml:"username"`
+    Password        string `toml:"password,omitempty"`
+    PasswordCommand string `toml:"password_command,omitempty"`
+    Calendar        string `toml:"calendar"`
+    Component       string `toml:"component"`
+}
+
+func (c caldavConfig) validate() error {
+    if c.Component != "VEVENT" && c.Component != "VTODO" {
+        return fmt.Errorf("component: want VEVENT or VTODO, got %q", c.Component)
+    }
+    if c.URL == "" {
+        return nil
+    }
+    u, err := url.Parse(c.URL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+        return fmt.Errorf("url: want an http(s) collection URL, got %q", c.URL)
+    }
+    if c.Password != "" && c.PasswordCommand != "" {
+        return fmt.Errorf("password: set either password or password_command, not both")
+    }
+    return nil
+}
+
+// password returns the configured password, running password_command when
+// that is how it is kept.
+func (c caldavConfig) password() (string, error) {
+    if c.PasswordCommand == "" {
+        return c.Password, nil
+    }
+    out, err := exec.Command("sh", "-c", c.PasswordCommand).Output()
+    if err != nil {
+        return "", fmt.Errorf("password_command: %v", err)
+    }
+    return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+var errPreconditionFailed = errors.New("changed on the server since it was last read")
+
+type caldavClient struct {
+    http     *http.Client
+    base     *url.URL
+    username string
+    password string
+}
+
+type remoteObject struct {
+    href string
+    etag string
+    data string
+}
+
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"/></c:filter>
+</c:calendar-query>`
+
+type multistatus struct {
+    Responses []struct {
+        Href     string `xml:"DAV: href"`
+        Propstat []struct {
+            Status string `xml:"DAV: status"`
+            Prop   struct {
+                ETag string `xml:"DAV: getetag"`
+                Data string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+            } `xml:"DAV: prop"`
+        } `xml:"DAV: propstat"`
+    } `xml:"DAV: response"`
+}
+
+func newCalDAVClient(c caldavConfig) (*caldavClient, error) {
+    base, err := url.Parse(c.URL)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasSuffix(base.Path, "/") {
+        base.Path += "/"
+    }
+    password, err := c.password()
+    if err != nil {
+        return nil, err
+    }
+    return &caldavClient{
+        http:     &http.Client{Timeout: 30 * time.Second},
+        base:     base,
+        username: c.Username,
+        password: password,
+    }, nil
+}
+
+func (c *caldavClient) do(method, href string, body string, header map[string]string) (*http.Response, error) {
+    ref, err := url.Parse(href)
+    if err != nil {
+        return nil, err
+    }
+    req, err := http.NewRequest(method, c.base.ResolveReference(ref).String(), strings.NewReader(body))
+    if err != nil {
+        return nil, err
+    }
+    if c.username != "" {
+        req.SetBasicAuth(c.username, c.password)
+    }
+    for k, v := range header {
+        req.Header.Set(k, v)
+    }
+    return c.http.Do(req)
+}
+
+// list fetches every calendar object in the collection with its ETag.
+func (c *caldavClient) list() ([]remoteObject, error) {
+    resp, err := c.do("REPORT", c.base.Path, calendarQuery, map[string]string{
+        "Depth":        "1",
+        "Content-Type": "application/xml; charset=utf-8",
+    })
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusMultiStatus {
+        return nil, fmt.Errorf("REPORT %s: %s", c.base.Path, resp.Status)
+    }
+
+    var ms multistatus
+    if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+        return nil, fmt.Errorf("REPORT %s: %v", c.base.Path, err)
+    }
+    var objects []remoteObject
+    for _, r := range ms.Responses {
+        for _, ps := range r.Propstat {
+            if !strings.Contains(ps.Status, " 200 ") || ps.Prop.Data == "" {
+                continue
+            }
+            ref, err := url.Parse(r.Href)
+            if err != nil {
+                continue
+            }
+            objects = append(objects, remoteObject{
+                href: c.base.ResolveReference(ref).Path,
+                etag: ps.Prop.ETag,
+                data: ps.Prop.Data,
+            })
+        }
+    }
+    return objects, nil
+}
+
+// put uploads an object. With an etag the write only happens if the server
+// still has that version; without one it only happens if href is new.
+func (c *caldavClient) put(href, data, etag string) (string, error) {
+    header := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+    if etag != "" {
+        header["If-Match"] = etag
+    } else {
+        header["If-None-Match"] = "*"
+    }
+    resp, err := c.do("PUT", href, data, header)
+    if err != nil {
+        return "", err
+    }
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusPreconditionFailed:
+        return "", errPreconditionFailed
+    case resp.StatusCode < 200 || resp.StatusCode > 299:
+        return "", fmt.Errorf("PUT %s: %s", href, resp.Status)
+    }
+    if etag := resp.Header.Get("ETag"); etag != "" {
+        return etag, nil
+    }
+
+    // Some servers only hand out the new ETag on a later read.
+    resp, err = c.do("HEAD", href, "", nil)
+    if err != nil {
+        return "", err
+    }
+    resp.Body.Close()
+    return resp.Header.Get("ETag"), nil
+}
+
+func (c *caldavClient) delete(href, etag string) error {
+    header := map[string]string{}
+    if etag != "" {
+        header["If-Match"] = etag
+    }
+    resp, err := c.do("DELETE", href, "", header)
+    if err != nil {
+        return err
+    }
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusPreconditionFailed:
+        return errPreconditionFailed
+    case resp.StatusCode == http.StatusNotFound:
+        return nil
+    case resp.StatusCode < 200 || resp.StatusCode > 299:
+        return fmt.Errorf("DELETE %s: %s", href, resp.Status)
+    }
+    return nil
+}
+
+type syncStats struct {
+    pulled, pushed, deletedLocal, deletedRemote, conflicts, skipped int
+}
+
+func (s syncStats) String() string {
+    return fmt.Sprintf("pulled %d, pushed %d, deleted %d locally and %d remotely, %d conflicts, %d skipped",
+        s.pulled, s.pushed, s.deletedLocal, s.deletedRemote, s.conflicts, s.skipped)
+}
+
+// caldavSync keeps one local calendar and one CalDAV collection in step.
+type caldavSync struct {
+    db          *db.DB
+    client      *caldavClient
+    settings    settings
+    calendar    db.Calendar
+    collection  string
+    preferLocal bool
+    stats       syncStats
+}
+
+type remoteItem struct {
+    remoteObject
+    item icalItem
+}
+
+// taskHash fingerprints the synced fields of a task so a later sync can tell
+// whether it was edited locally.
+func taskHash(t db.Task) string {
+    sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d\x00%s\x00%d\x00%t",
+        t.Title, t.StartsAt.Unix(), t.TZ, t.Duration, t.Done)))
+    return hex.EncodeToString(sum[:])
+}
+
+func newUID() string {
+    b := make([]byte, 16)
+    rand.Read(b)
+    return hex.EncodeToString(b) + "@scheduler"
+}
+
+// run pulls remote changes and pushes local ones. A task edited on both
+// sides since the last sync is a conflict, settled in favour of the remote
+// copy unless preferLocal is set.
+func (s *caldavSync) run() error {
+    objects, err := s.client.list()
+    if err != nil {
+        return err
+    }
+    remote := make(map[string]remoteItem)
+    for _, obj := range objects {
+        item, err := parseICal(obj.data, s.settings.location)
+        if err != nil {
+            log.Printf("skipping %s: %v", obj.href, err)
+            s.stats.skipped++
+            continue
+        }
+        if item.allDay {
+            s.stats.skipped++
+            continue
+        }
+        remote[item.uid] = remoteItem{obj, item}
+    }
+
+    links, err := s.db.CalDAVLinks(s.collection)
+    if err != nil {
+        return err
+    }
+    list, err := s.db.GetCalendarTasks(s.calendar.ID)
+    if err != nil {
+        return err
+    }
+    tasks := make(map[int64]db.Task, len(list))
+    for _, t := range list {
+        tasks[t.ID] = t
+    }
+
+    linked := make(map[int64]bool)
+    for _, link := range links {
+        linked[link.TaskID] = true
+        r, haveRemote := remote[link.UID]
+        delete(remote, link.UID)
+        if err := s.syncLinked(link, r, haveRemote, tasks); err != nil {
+            return err
+        }
+    }
+
+    for _, r := range remote {
+        if err := s.pull(nil, r, db.CalDAVLink{UID: r.item.uid}); err != nil {
+            return err
+        }
+    }
+    for _, t := range list {
+        if linked[t.ID] {
+            continue
+        }
+        link := db.CalDAVLink{UID: newUID()}
+        link.Href = s.client.base.Path + strings.TrimSuffix(link.UID, "@scheduler") + ".ics"
+        if err := s.push(t, link, "", s.settings.caldav.Component); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *caldavSync) syncLinked(link db.CalDAVLink, r remoteItem, haveRemote bool, tasks map[int64]db.Task) error {
+    task, haveTask := tasks[link.TaskID]
+    if !haveTask {
+        // Moved to another calendar since the last sync. The task still owns
+        // its remote copy, so it keeps syncing with it from there; only a
+        // task that is really gone is deleted remotely.
+        moved, err := s.db.GetTask(link.TaskID)
+        switch {
+        case err == nil:
+            task, haveTask = moved, true
+        case !errors.Is(err, db.ErrTaskNotFound):
+            return err
+        }
+    }
+    localChanged := haveTask && taskHash(task) != link.Hash
+    remoteChanged := haveRemote && r.etag != link.ETag
+
+    switch {
+    case haveTask && haveRemote:
+        switch {
+        case localChanged && remoteChanged:
+            s.conflict(task.Title)
+            if s.preferLocal {
+                return s.push(task, link, r.etag, r.item.component)
+            }
+            return s.pull(&task, r, link)
+        case localChanged:
+            return s.push(task, link, link.ETag, r.item.component)
+        case remoteChanged:
+            return s.pull(&task, r, link)
+        }
+        return nil
+
+    case haveTask:
+        // Deleted on the server.
+        if localChanged {
+            s.conflict(task.Title)
+            if s.preferLocal {
+                link.ETag = ""
+                return s.push(task, link, "", s.settings.caldav.Component)
+            }
+        }
+        if err := s.db.DeleteTask(task.ID); err != nil {
+            return err
+        }
+        s.stats.deletedLocal++
+        return s.db.DeleteCalDAVLink(link.TaskID)
+
+    case haveRemote:
+        // Deleted here.
+        if remoteChanged {
+            s.conflict(r.item.title)
+            if !s.preferLocal {
+                if err := s.db.DeleteCalDAVLink(link.TaskID); err != nil {
+                    return err
+                }
+                return s.pull(nil, r, db.CalDAVLink{UID: link.UID})
+            }
+        }
+        if err := s.client.delete(r.href, r.etag); err != nil {
+            if errors.Is(err, errPreconditionFailed) {
+                s.conflict(r.item.title)
+                return nil
+            }
+            return err
+        }
+        s.stats.deletedRemote++
+        return s.db.DeleteCalDAVLink(link.TaskID)
+    }
+    return s.db.DeleteCalDAVLink(link.TaskID)
+}
+
+func (s *caldavSync) conflict(title string) {
+    s.stats.conflicts++
+    keep := "remote"
+    if s.preferLocal {
+        keep = "local"
+    }
+    log.Printf("conflict: %q changed on both sides, keeping the %s copy", title, keep)
+}
+
+// pull writes a remote item into task, or into a new task when task is nil,
+// and records the link.
+func (s *caldavSync) pull(task *db.Task, r remoteItem, link db.CalDAVLink) error {
+    t := db.Task{CalendarID: s.calendar.ID, StartsAt: r.item.startsAt.In(s.settings.location)}
+    if task != nil {
+        t = *task
+        t.StartsAt = r.item.startsAt.In(task.StartsAt.Location())
+    }
+
+    t.Title = strings.TrimSpace(r.item.title)
+    if t.Title == "" {
+        t.Title = "(untitled)"
+    }
+    if runes := []rune(t.Title); len(runes) > s.settings.titleCharLimit {
+        t.Title = string(runes[:s.settings.titleCharLimit])
+    }
+    t.Duration = r.item.duration
+    switch {
+    case t.Duration <= 0:
+        t.Duration = s.settings.defaultDuration
+    case t.Duration > 999:
+        t.Duration = 999
+    }
+    t.Done = r.item.done
+
+    if task != nil {
+        if err := s.db.UpdateTask(t); err != nil {
+            return err
+        }
+    } else {
+        id, err := s.db.SaveTask(t.CalendarID, t.StartsAt, t.Title, t.Duration)
+        if err != nil {
+            return err
+        }
+        t.ID = id
+        if t.Done {
+            if err := s.db.UpdateTaskDone(id, true); err != nil {
+                return err
+            }
+        }
+    }
+
+    saved, err := s.db.GetTask(t.ID)
+    if err != nil {
+        return err
+    }
+    link.TaskID = saved.ID
+    link.Collection = s.collection
+    link.Href = r.href
+    link.ETag = r.etag
+    link.Hash = taskHash(saved)
+    s.stats.pulled++
+    return s.db.SaveCalDAVLink(link)
+}
+
+// push uploads task over the object at link.Href, expecting the server to
+// still have etag ("" for a new object).
+func (s *caldavSync) push(task db.Task, link db.CalDAVLink, etag, component string) error {
+    data := encodeICal(icalItem{
+        uid:       link.UID,
+        component: component,
+        title:     task.Title,
+        startsAt:  task.StartsAt,
+        duration:  task.Duration,
+        done:      task.Done,
+    })
+    newETag, err := s.client.put(link.Href, data, etag)
+    if errors.Is(err, errPreconditionFailed) {
+        // Picked up as a remote change on the next sync.
+        s.conflict(task.Title)
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    link.TaskID = task.ID
+    link.Collection = s.collection
+    link.ETag = newETag
+    link.Hash = taskHash(task)
+    s.stats.pushed++
+    return s.db.SaveCalDAVLink(link)
+}
+
+func runCalDAV(args []string) error {
+    if len(args) == 0 || args[0] != "sync" {
+        return errors.New("usage: scheduler caldav sync [-prefer remote|local]")
+    }
+    fs := flag.NewFlagSet("caldav sync", flag.ContinueOnError)
+    prefer := fs.String("prefer", "remote", "which copy wins when a task changed on both sides: remote or local")
+    if err := fs.Parse(args[1:]); err != nil {
+        return err
+    }
+    if *prefer != "remote" && *prefer != "local" {
+        return fmt.Errorf("-prefer: want remote or local, got %q", *prefer)
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    if s.caldav.URL == "" {
+        return errors.New("caldav.url is not set in the config file")
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    calendar, err := database.CalendarByName(s.caldav.Calendar)
+    if err != nil {
+        return fmt.Errorf("caldav.calendar: %v", err)
+    }
+    client, err := newCalDAVClient(s.caldav)
+    if err != nil {
+        return err
+    }
+
+    sync := &caldavSync{
+        db:          database,
+        client:      client,
+        settings:    s,
+        calendar:    calendar,
+        collection:  client.base.String(),
+        preferLocal: *prefer == "local",
+    }
+    if err := sync.run(); err != nil {
+        return err
+    }
+    fmt.Println(sync.stats)
+    return nil
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "sort"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "scheduler/db"
+)
+
+// fakeCalDAV is an in-memory CalDAV collection at /cal/ that answers
+// REPORT, PUT, HEAD and DELETE with ETags and honours If-Match and
+// If-None-Match.
+type fakeCalDAV struct {
+    mu      sync.Mutex
+    objects map[string]fakeObject
+    version int
+    // beforePut, when set, runs before a PUT is checked, as if another
+    // client wrote first.
+    beforePut func(href string)
+}
+
+type fakeObject struct {
+    etag string
+    data string
+}
+
+func newFakeCalDAV(t *testing.T) (*fakeCalDAV, *caldavClient) {
+    t.Helper()
+    f := &fakeCalDAV{objects: make(map[string]fakeObject)}
+    srv := httptest.NewServer(f)
+    t.Cleanup(srv.Close)
+    client, err := newCalDAVClient(caldavConfig{URL: srv.URL + "/cal/", Component: "VEVENT"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    return f, client
+}
+
+// store writes an object as another client would and returns its new ETag.
+func (f *fakeCalDAV) store(href, data string) string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.storeLocked(href, data)
+}
+
+func (f *fakeCalDAV) storeLocked(href, data string) string {
+    f.version++
+    etag := fmt.Sprintf(`"%d"`, f.version)
+    f.objects[href] = fakeObject{etag: etag, data: data}
+    return etag
+}
+
+func (f *fakeCalDAV) remove(href string) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    delete(f.objects, href)
+}
+
+func (f *fakeCalDAV) hrefs() []string {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    var hrefs []string
+    for href := range f.objects {
+        hrefs = append(hrefs, href)
+    }
+    sort.Strings(hrefs)
+    return hrefs
+}
+
+func (f *fakeCalDAV) get(href string) fakeObject {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.objects[href]
+}
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method == http.MethodPut && f.beforePut != nil {
+        hook := f.beforePut
+        f.beforePut = nil
+        hook(r.URL.Path)
+    }
+
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    href := r.URL.Path
+    obj, exists := f.objects[href]
+
+    switch r.Method {
+    case "REPORT":
+        if href != "/cal/" {
+            http.NotFound(w, r)
+            return
+        }
+        var b strings.Builder
+        b.WriteString(`<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+        for h, o := range f.objects {
+            b.WriteString("<d:response><d:href>" + h + "</d:href><d:propstat><d:prop><d:getetag>")
+            xml.EscapeText(&b, []byte(o.etag))
+            b.WriteString("</d:getetag><c:calendar-data>")
+            xml.EscapeText(&b, []byte(o.data))
+            b.WriteString("</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>")
+        }
+        b.WriteString("</d:multistatus>")
+        w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+        w.WriteHeader(http.StatusMultiStatus)
+        io.WriteString(w, b.String())
+
+    case http.MethodPut:
+        if m := r.Header.Get("If-Match"); m != "" && (!exists || m != obj.etag) {
+            w.WriteHeader(http.StatusPreconditionFailed)
+            return
+        }
+        if r.Header.Get("If-None-Match") == "*" && exists {
+            w.WriteHeader(http.StatusPreconditionFailed)
+            return
+        }
+        data, _ := io.ReadAll(r.Body)
+        w.Header().Set("ETag", f.storeLocked(href, string(data)))
+        w.WriteHeader(http.StatusCreated)
+
+    case http.MethodHead:
+        if !exists {
+            http.NotFound(w, r)
+            return
+        }
+        w.Header().Set("ETag", obj.etag)
+
+    case http.MethodDelete:
+        if !exists {
+            http.NotFound(w, r)
+            return
+        }
+        if m := r.Header.Get("If-Match"); m != "" && m != obj.etag {
+            w.WriteHeader(http.StatusPreconditionFailed)
+            return
+        }
+        delete(f.objects, href)
+        w.WriteHeader(http.StatusNoContent)
+
+    default:
+        w.WriteHeader(http.StatusMethodNotAllowed)
+    }
+}
+
+func remoteEvent(uid, dtstart, summary string) string {
+    return strings.Join([]string{
+        "BEGIN:VCALENDAR",
+        "VERSION:2.0",
+        "PRODID:-//test//EN",
+        "BEGIN:VEVENT",
+        "UID:" + uid,
+        "DTSTART;TZID=America/New_York:" + dtstart,
+        "DURATION:PT45M",
+        "SUMMARY:" + summary,
+        "END:VEVENT",
+        "END:VCALENDAR",
+    }, "\r\n") + "\r\n"
+}
+
+type caldavFixture struct {
+    server   *fakeCalDAV
+    db       *db.DB
+    settings settings
+    calendar db.Calendar
+    client   *caldavClient
+}
+
+func newCalDAVFixture(t *testing.T) *caldavFixture {
+    t.Helper()
+    server, client := newFakeCalDAV(t)
+    database := openTestDB(t)
+    calendar, err := database.CalendarByName("Default")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return &caldavFixture{
+        server:   server,
+        db:       database,
+        settings: testSettings(t, newYork(t)),
+        calendar: calendar,
+        client:   client,
+    }
+}
+
+func (f *caldavFixture) sync(t *testing.T, preferLocal bool) syncStats {
+    t.Helper()
+    s := &caldavSync{
+        db:          f.db,
+        client:      f.client,
+        settings:    f.settings,
+        calendar:    f.calendar,
+        collection:  f.client.base.String(),
+        preferLocal: preferLocal,
+    }
+    if err := s.run(); err != nil {
+        t.Fatal(err)
+    }
+    return s.stats
+}
+
+func (f *caldavFixture) tasks(t *testing.T) []db.Task {
+    t.Helper()
+    tasks, err := f.db.GetCalendarTasks(f.calendar.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return tasks
+}
+
+func TestCalDAVPull(t *testing.T) {
+    f := newCalDAVFixture(t)
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Dentist"))
+
+    stats := f.sync(t, false)
+    if stats.pulled != 1 || stats.pushed != 0 {
+        t.Fatalf("first sync: %v", stats)
+    }
+    tasks := f.tasks(t)
+    if len(tasks) != 1 {
+        t.Fatalf("got %d tasks, want 1", len(tasks))
+    }
+    task := tasks[0]
+    want := time.Date(2026, 10, 19, 13, 30, 0, 0, time.UTC)
+    if task.Title != "Dentist" || !task.StartsAt.Equal(want) || task.Duration != 45 {
+        t.Errorf("pulled %q at %v for %dm, want Dentist at %v for 45m", task.Title, task.StartsAt, task.Duration, want)
+    }
+
+    if stats := f.sync(t, false); stats != (syncStats{}) {
+        t.Errorf("sync without changes: %v", stats)
+    }
+
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T100000", "Dentist, moved"))
+    if stats := f.sync(t, false); stats.pulled != 1 {
+        t.Fatalf("sync after remote edit: %v", stats)
+    }
+    task, err := f.db.GetTask(task.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if task.Title != "Dentist, moved" || task.StartsAt.In(time.UTC).Hour() != 14 {
+        t.Errorf("after remote edit got %q at %v", task.Title, task.StartsAt)
+    }
+}
+
+func TestCalDAVPush(t *testing.T) {
+    f := newCalDAVFixture(t)
+    startsAt := time.Date(2026, 10, 19, 9, 0, 0, 0, f.settings.location)
+    id, err := f.db.SaveTask(f.calendar.ID, startsAt, "Write report", 60)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if stats := f.sync(t, false); stats.pushed != 1 || stats.pulled != 0 {
+        t.Fatalf("first sync: %v", stats)
+    }
+    hrefs := f.server.hrefs()
+    if len(hrefs) != 1 {
+        t.Fatalf("server has %d objects, want 1", len(hrefs))
+    }
+    item, err := parseICal(f.server.get(hrefs[0]).data, time.UTC)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if item.title != "Write report" || !item.startsAt.Equal(startsAt) || item.duration != 60 {
+        t.Errorf("pushed %q at %v for %dm", item.title, item.startsAt, item.duration)
+    }
+
+    if stats := f.sync(t, false); stats != (syncStats{}) {
+        t.Errorf("sync without changes: %v", stats)
+    }
+
+    task, _ := f.db.GetTask(id)
+    task.Title = "Write the report"
+    if err := f.db.UpdateTask(task); err != nil {
+        t.Fatal(err)
+    }
+    etag := f.server.get(hrefs[0]).etag
+    if stats := f.sync(t, false); stats.pushed != 1 {
+        t.Fatalf("sync after local edit: %v", stats)
+    }
+    if got := f.server.get(hrefs[0]); got.etag == etag || !strings.Contains(got.data, "SUMMARY:Write the report") {
+        t.Errorf("local edit was not uploaded: %q", got.data)
+    }
+}
+
+func TestCalDAVPreconditionFailed(t *testing.T) {
+    f := newCalDAVFixture(t)
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Standup"))
+    f.sync(t, false)
+    task := f.tasks(t)[0]
+
+    // Edited here, and by another client between our REPORT and our PUT.
+    task.Title = "Standup (local)"
+    if err := f.db.UpdateTask(task); err != nil {
+        t.Fatal(err)
+    }
+    f.server.beforePut = func(href string) {
+        f.server.store(href, remoteEvent("a@test", "20261019T093000", "Standup (remote)"))
+    }
+    stats := f.sync(t, false)
+    if stats.conflicts != 1 || stats.pushed != 0 {
+        t.Fatalf("sync racing a remote edit: %v", stats)
+    }
+    if data := f.server.get("/cal/a.ics").data; !strings.Contains(data, "Standup (remote)") {
+        t.Errorf("remote copy was overwritten: %q", data)
+    }
+
+    // The next sync sees both edits and keeps the remote one by default.
+    stats = f.sync(t, false)
+    if stats.conflicts != 1 || stats.pulled != 1 {
+        t.Fatalf("sync after the race: %v", stats)
+    }
+    task, _ = f.db.GetTask(task.ID)
+    if task.Title != "Standup (remote)" {
+        t.Errorf("got title %q, want the remote one", task.Title)
+    }
+}
+
+func TestCalDAVPreferLocal(t *testing.T) {
+    f := newCalDAVFixture(t)
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Standup"))
+    f.sync(t, false)
+    task := f.tasks(t)[0]
+
+    task.Title = "Standup (local)"
+    if err := f.db.UpdateTask(task); err != nil {
+        t.Fatal(err)
+    }
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Standup (remote)"))
+    stats := f.sync(t, true)
+    if stats.conflicts != 1 || stats.pushed != 1 {
+        t.Fatalf("sync preferring local: %v", stats)
+    }
+    if data := f.server.get("/cal/a.ics").data; !strings.Contains(data, "Standup (local)") {
+        t.Errorf("local copy was not uploaded: %q", data)
+    }
+}
+
+func TestCalDAVDeletes(t *testing.T) {
+    f := newCalDAVFixture(t)
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Removed remotely"))
+    f.server.store("/cal/b.ics", remoteEvent("b@test", "20261019T110000", "Removed locally"))
+    f.sync(t, false)
+    tasks := f.tasks(t)
+    if len(tasks) != 2 {
+        t.Fatalf("got %d tasks, want 2", len(tasks))
+    }
+
+    f.server.remove("/cal/a.ics")
+    for _, task := range tasks {
+        if task.Title == "Removed locally" {
+            if err := f.db.DeleteTask(task.ID); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    stats := f.sync(t, false)
+    if stats.deletedLocal != 1 || stats.deletedRemote != 1 {
+        t.Fatalf("sync after deletes: %v", stats)
+    }
+    if tasks := f.tasks(t); len(tasks) != 0 {
+        t.Errorf("%d tasks left, want none", len(tasks))
+    }
+    if hrefs := f.server.hrefs(); len(hrefs) != 0 {
+        t.Errorf("server still has %v", hrefs)
+    }
+    links, err := f.db.CalDAVLinks(f.client.base.String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(links) != 0 {
+        t.Errorf("%d links left, want none", len(links))
+    }
+}
+
+func TestCalDAVMovedToAnotherCalendar(t *testing.T) {
+    f := newCalDAVFixture(t)
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T093000", "Dentist"))
+    f.sync(t, false)
+    task := f.tasks(t)[0]
+
+    personal, err := f.db.CreateCalendar("Personal", "212")
+    if err != nil {
+        t.Fatal(err)
+    }
+    task.CalendarID = personal.ID
+    if err := f.db.UpdateTask(task); err != nil {
+        t.Fatal(err)
+    }
+    if stats := f.sync(t, false); stats != (syncStats{}) {
+        t.Fatalf("sync after the move: %v", stats)
+    }
+    if hrefs := f.server.hrefs(); len(hrefs) != 1 {
+        t.Fatalf("server has %v, want the moved task's copy kept", hrefs)
+    }
+
+    // The link survives the move, so remote edits still reach the task.
+    f.server.store("/cal/a.ics", remoteEvent("a@test", "20261019T100000", "Dentist, moved"))
+    if stats := f.sync(t, false); stats.pulled != 1 {
+        t.Fatalf("sync after remote edit: %v", stats)
+    }
+    got, err := f.db.GetTask(task.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got.Title != "Dentist, moved" || got.CalendarID != personal.ID {
+        t.Errorf("got %q in calendar %d, want the remote edit in %d", got.Title, got.CalendarID, personal.ID)
+    }
+    if tasks := f.tasks(t); len(tasks) != 0 {
+        t.Errorf("%d tasks back in the synced calendar, want none", len(tasks))
+    }
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "text/tabwriter"
+    "time"
+
+    "github.com/charmbracelet/lipgloss"
+
+    "scheduler/db"
+)
+
+// calendarPalette colors new calendars that were not given one.
+var calendarPalette = []string{"39", "214", "170", "78", "203", "141"}
+
+func (m *model) loadCalendars() error {
+    calendars, err := m.db.Calendars()
+    if err != nil {
+        return err
+    }
+    m.calendars = calendars
+
+    if _, ok := m.calendarByID(m.calendar); !ok && len(calendars) > 0 {
+        m.calendar = calendars[0].ID
+        for _, c := range calendars {
+            if !c.Hidden {
+                m.calendar = c.ID
+                break
+            }
+        }
+    }
+    if m.calendarCursor >= len(calendars) {
+        m.calendarCursor = len(calendars) - 1
+    }
+    return nil
+}
+
+func (m model) calendarByID(id int64) (db.Calendar, bool) {
+    for _, c := range m.calendars {
+        if c.ID == id {
+            return c, true
+        }
+    }
+    return db.Calendar{}, false
+}
+
+func (m *model) openCalendars() {
+    m.mode = calendarMode
+    m.calendarCursor = 0
+    for i, c := range m.calendars {
+        if c.ID == m.calendar {
+            m.calendarCursor = i
+        }
+    }
+    m.calendarNote = ""
+    m.applyLayout()
+}
+
+func (m *model) toggleCalendar() {
+    if m.calendarCursor < 0 || m.calendarCursor >= len(m.calendars) {
+        return
+    }
+    c := m.calendars[m.calendarCursor]
+    if !c.Hidden && c.ID == m.calendar {
+        m.calendarNote = "New tasks go here; pick another calendar before hiding it"
+        return
+    }
+    if err := m.db.SetCalendarHidden(c.ID, !c.Hidden); err != nil {
+        m.calendarNote = fmt.Sprintf("Failed to update calendar: %v", err)
+        return
+    }
+    m.calendarNote = ""
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+    m.updateViewport()
+}
+
+// useCalendar makes the calendar under the cursor the one new tasks go into,
+// showing it again if it was hidden.
+func (m *model) useCalendar() {
+    if m.calendarCursor < 0 || m.calendarCursor >= len(m.calendars) {
+        return
+    }
+    c := m.calendars[m.calendarCursor]
+    m.calendar = c.ID
+    if c.Hidden {
+        m.toggleCalendar()
+    }
+    m.calendarNote = ""
+}
+
+func calendarDot(c db.Calendar) string {
+    style := lipgloss.NewStyle()
+    if c.Color != "" {
+        style = style.Foreground(lipgloss.Color(c.Color))
+    }
+    return style.Render("●")
+}
+
+func (m model) calendarsView() string {
+    var b strings.Builder
+    b.WriteString("Calendars\n")
+    for i, c := range m.calendars {
+        cursor := "  "
+        if i == m.calendarCursor {
+            cursor = "> "
+        }
+        shown := "[x]"
+        if c.Hidden {
+            shown = "[ ]"
+        }
+        line := fmt.Sprintf("%s %s", shown, c.Name)
+        if c.ID == m.calendar {
+            line += " (new tasks)"
+        }
+        b.WriteString("\n" + cursor + calendarDot(c) + " " + truncate(line, m.contentWidth()-10))
+    }
+    if m.calendarNote != "" {
+        b.WriteString("\n\n" + m.calendarNote)
+    }
+    return formStyle.Render(b.String())
+}
+
+func runCalendar(args []string) error {
+    usage := "usage: scheduler calendar list | add NAME [-color COLOR] | color NAME COLOR | show NAME | hide NAME"
+    if len(args) == 0 {
+        return errors.New(usage)
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return err
+    }
+    defer database.Close()
+
+    switch args[0] {
+    case "list":
+        calendars, err := database.Calendars()
+        if err != nil {
+            return err
+        }
+        w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(w, "NAME\tCOLOR\tVISIBLE")
+        for _, c := range calendars {
+            fmt.Fprintf(w, "%s\t%s\t%t\n", c.Name, c.Color, !c.Hidden)
+        }
+        return w.Flush()
+
+    case "add":
+        fs := flag.NewFlagSet("calendar add", flag.ContinueOnError)
+        color := fs.String("color", "", "color for the calendar's tasks (ANSI number or #rgb hex)")
+        if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+            return errors.New("usage: scheduler calendar add NAME [-color COLOR]")
+        }
+        if err := fs.Parse(args[2:]); err != nil {
+            return err
+        }
+        if *color == "" {
+            calendars, err := database.Calendars()
+            if err != nil {
+                return err
+            }
+            *color = calendarPalette[len(calendars)%len(calendarPalette)]
+        }
+        if !colorPattern.MatchString(*color) {
+            return fmt.Errorf("invalid color %q", *color)
+        }
+        _, err := database.CreateCalendar(args[1], *color)
+        return err
+
+    case "color", "show", "hide":
+        if len(args) < 2 || (args[0] == "color" && len(args) < 3) {
+            return errors.New(usage)
+        }
+        c, err := database.CalendarByName(args[1])
+        if err != nil {
+            return err
+        }
+        switch args[0] {
+        case "color":
+            if !colorPattern.MatchString(args[2]) {
+                return fmt.Errorf("invalid color %q", args[2])
+            }
+            return database.SetCalendarColor(c.ID, args[2])
+        case "show":
+            return database.SetCalendarHidden(c.ID, false)
+        }
+        return database.SetCalendarHidden(c.ID, true)
+    }
+    return errors.New(usage)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+    "fmt"
+)
+
+func runCommand(name string, args []string) error {
+    switch name {
+    case "daemon":
+        return runDaemon(args)
+    case "report":
+        return runReport(args)
+    case "config":
+        return runConfig(args)
+    case "calendar":
+        return runCalendar(args)
+    case "serve":
+        return runServe(args)
+    case "rpc":
+        return runRPC(args)
+    case "caldav":
+        return runCalDAV(args)
+    case "sync":
+        return runSync(args)
+    case "backup":
+        return runBackup(args)
+    case "restore":
+        return runRestore(args)
+    case "notes":
+        return runNotes(args)
+    case "org":
+        return runOrg(args)
+    case "todotxt":
+        return runTodoTxt(args)
+    default:
+        return fmt.Errorf("unknown command %q", name)
+    }
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+)
+
+type focusConfig struct {
+    Work  time.Duration `toml:"work"`
+    Break time.Duration `toml:"break"`
+}
+
+// config mirrors config.toml. Anything left out of the file keeps the value
+// from defaultConfig.
+type config struct {
+    DBPath          string            `toml:"db_path"`
+    SlotMinutes     int               `toml:"slot_minutes"`
+    DefaultDuration int               `toml:"default_duration"`
+    TitleCharLimit  int               `toml:"title_char_limit"`
+    Timezone        string            `toml:"timezone"`
+    WorkHours       map[string]string `toml:"work_hours"`
+    Focus           focusConfig       `toml:"focus"`
+    Theme           string            `toml:"theme"`
+    Colors          colorConfig       `toml:"colors"`
+    Keys            keysConfig        `toml:"keys"`
+    CalDAV          caldavConfig      `toml:"caldav"`
+    Backup          backupConfig      `toml:"backup"`
+    Notes           notesConfig       `toml:"notes"`
+
+    dir string
+}
+
+type settings struct {
+    dbPath          string
+    slotMinutes     int
+    defaultDuration int
+    titleCharLimit  int
+    location        *time.Location
+    workHours       [7]dayWindow
+    focusWork       time.Duration
+    focusBreak      time.Duration
+    colors          colorConfig
+    lightColors     colorConfig
+    keys            keyMap
+    caldav          caldavConfig
+    backup          backupConfig
+    notes           notesConfig
+}
+
+func defaultConfig() config {
+    return config{
+        DBPath:          "~/.scheduler/scheduler.db",
+        SlotMinutes:     30,
+        DefaultDuration: 30,
+        TitleCharLimit:  50,
+        Timezone:        "local",
+        WorkHours:       map[string]string{},
+        Focus: focusConfig{
+            Work:  25 * time.Minute,
+            Break: 5 * time.Minute,
+        },
+        Theme: "auto",
+        Keys: keysConfig{
+            Preset: "default",
+        },
+        CalDAV: caldavConfig{
+            Calendar:  "Default",
+            Component: "VEVENT",
+        },
+        Backup: backupConfig{
+            Dir:         "~/.scheduler/backups",
+            Schedule:    "daily",
+            KeepDaily:   7,
+            KeepWeekly:  4,
+            KeepMonthly: 6,
+        },
+        Notes: notesConfig{
+            Filename:    "{date}.md",
+            Frontmatter: map[string]string{},
+        },
+    }
+}
+
+// configPath is $SCHEDULER_CONFIG if set, otherwise config.toml in the
+// user's config directory (~/.config/scheduler on Linux).
+func configPath() (string, error) {
+    if path := os.Getenv("SCHEDULER_CONFIG"); path != "" {
+        return path, nil
+    }
+    dir, err := os.UserConfigDir()
+    if err != nil {
+        return "", fmt.Errorf("failed to get config directory: %v", err)
+    }
+    return filepath.Join(dir, "scheduler", "config.toml"), nil
+}
+
+func loadConfig(path string) (config, error) {
+    c := defaultConfig()
+    c.dir = filepath.Dir(path)
+
+    md, err := toml.DecodeFile(path, &c)
+    if errors.Is(err, fs.ErrNotExist) {
+        return c, nil
+    }
+    if err != nil {
+        var perr toml.ParseError
+        if errors.As(err, &perr) {
+            return c, fmt.Errorf("%s: %s", path, perr.ErrorWithPosition())
+        }
+        return c, fmt.Errorf("%s: %v", path, err)
+    }
+
+    if undecoded := md.Undecoded(); len(undecoded) > 0 {
+        keys := make([]string, len(undecoded))
+        for i, key := range undecoded {
+            keys[i] = fmt.Sprintf("%q", key.String())
+        }
+        return c, fmt.Errorf("%s: unknown key %s", path, strings.Join(keys, ", "))
+    }
+
+    return c, nil
+}
+
+func (c config) settings() (settings, error) {
+    s := settings{
+        slotMinutes:     c.SlotMinutes,
+        defaultDuration: c.DefaultDuration,
+        titleCharLimit:  c.TitleCharLimit,
+        focusWork:       c.Focus.Work,
+        focusBreak:      c.Focus.Break,
+    }
+
+    if c.DBPath == "" {
+        return s, fmt.Errorf("db_path: must not be empty")
+    }
+    s.dbPath = expandHome(c.DBPath)
+
+    s.location = time.Local
+    if c.Timezone != "" && c.Timezone != "local" {
+        loc, err := time.LoadLocation(c.Timezone)
+        if err != nil {
+            return s, fmt.Errorf("timezone: unknown zone %q, want an IANA name such as Europe/Berlin or \"local\"", c.Timezone)
+        }
+        s.location = loc
+    }
+
+    if c.SlotMinutes <= 0 || 60%c.SlotMinutes != 0 {
+        return s, fmt.Errorf("slot_minutes: %d must be a number of minutes that divides an hour (e.g. 15, 30, 60)", c.SlotMinutes)
+    }
+    if c.DefaultDuration <= 0 || c.DefaultDuration > 999 {
+        return s, fmt.Errorf("default_duration: %d must be between 1 and 999 minutes", c.DefaultDuration)
+    }
+    if c.TitleCharLimit <= 0 {
+        return s, fmt.Errorf("title_char_limit: %d must be positive", c.TitleCharLimit)
+    }
+    if c.Focus.Work <= 0 {
+        return s, fmt.Errorf("focus.work: %v must be positive", c.Focus.Work)
+    }
+    if c.Focus.Break <= 0 {
+        return s, fmt.Errorf("focus.break: %v must be positive", c.Focus.Break)
+    }
+
+    keys, err := c.Keys.keyMap()
+    if err != nil {
+        return s, err
+    }
+    s.keys = keys
+
+    hours, err := parseWorkHours(c.WorkHours)
+    if err != nil {
+        return s, fmt.Errorf("work_hours: %v", err)
+    }
+    s.workHours = hours
+
+    // Both variants are resolved here so a bad theme is reported by every
+    // command, but only the TUI asks the terminal which one it needs.
+    for _, dark := range []bool{true, false} {
+        theme, err := loadTheme(c.Theme, filepath.Join(c.dir, "themes"), dark)
+        if err != nil {
+            return s, fmt.Errorf("theme: %v", err)
+        }
+        colors := theme.merge(c.Colors)
+        if err := colors.validate(); err != nil {
+            return s, fmt.Errorf("colors.%v", err)
+        }
+        if dark {
+            s.colors = colors
+        } else {
+            s.lightColors = colors
+        }
+    }
+
+    if err := c.CalDAV.validate(); err != nil {
+        return s, fmt.Errorf("caldav.%v", err)
+    }
+    s.caldav = c.CalDAV
+
+    if err := c.Backup.validate(); err != nil {
+        return s, fmt.Errorf("backup.%v", err)
+    }
+    s.backup = c.Backup
+    s.backup.Dir = expandHome(c.Backup.Dir)
+
+    if err := c.Notes.validate(); err != nil {
+        return s, fmt.Errorf("notes.%v", err)
+    }
+    s.notes = c.Notes
+    s.notes.Dir = expandHome(c.Notes.Dir)
+
+    return s, nil
+}
+
+func expandHome(path string) string {
+    if path != "~" && !strings.HasPrefix(path, "~/") {
+        return path
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return path
+    }
+    return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+func loadSettings() (settings, error) {
+    path, err := configPath()
+    if err != nil {
+        return settings{}, err
+    }
+    c, err := loadConfig(path)
+    if err != nil {
+        return settings{}, err
+    }
+    s, err := c.settings()
+    if err != nil {
+        return s, fmt.Errorf("%s: %v", path, err)
+    }
+    return s, nil
+}
+
+func runConfig(args []string) error {
+    if len(args) > 0 {
+        return fmt.Errorf("config takes no arguments")
+    }
+
+    path, err := configPath()
+    if err != nil {
+        return err
+    }
+    c, err := loadConfig(path)
+    if err != nil {
+        return err
+    }
+    if _, err := c.settings(); err != nil {
+        return fmt.Errorf("%s: %v", path, err)
+    }
+
+    if _, err := os.Stat(path); err != nil {
+        fmt.Printf("# %s not found, showing defaults\n", path)
+    } else {
+        fmt.Printf("# effective configuration from %s\n", path)
+    }
+    if c.CalDAV.Password != "" {
+        c.CalDAV.Password = "********"
+    }
+    return toml.NewEncoder(os.Stdout).Encode(c)
+}
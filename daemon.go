@@ -0,0 +1,251 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "log"
+    "os"
+    "os/exec"
+    "os/signal"
+    "sort"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "scheduler/db"
+)
+
+const systemdUnit = `[Unit]
+Description=Scheduler reminder daemon
+
+[Service]
+ExecStart=%s daemon -lead %s
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`
+
+type reminder struct {
+    taskID   int64
+    title    string
+    startsAt time.Time
+    at       time.Time
+}
+
+type daemon struct {
+    db        *db.DB
+    lead      time.Duration
+    retry     time.Duration
+    loc       *time.Location
+    backup    backupConfig
+    reminders []reminder
+    version   int64
+    loadedDay string
+}
+
+func runDaemon(args []string) error {
+    fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+    lead := fs.Duration("lead", 5*time.Minute, "how long before a task starts to send its reminder")
+    poll := fs.Duration("poll", 15*time.Second, "how often to check the database for changes")
+    unit := fs.Bool("unit", false, "print a systemd user unit for the daemon and exit")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    if *unit {
+        exe, err := os.Executable()
+        if err != nil {
+            return fmt.Errorf("failed to locate executable: %v", err)
+        }
+        fmt.Printf(systemdUnit, systemdQuote(exe), *lead)
+        return nil
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    d := &daemon{db: database, lead: *lead, retry: *poll, loc: s.location, backup: s.backup}
+    return d.run(ctx, *poll)
+}
+
+func (d *daemon) run(ctx context.Context, poll time.Duration) error {
+    if err := d.reload(); err != nil {
+        return err
+    }
+    log.Printf("daemon started, %d upcoming reminders", len(d.reminders))
+
+    ticker := time.NewTicker(poll)
+    defer ticker.Stop()
+    timer := time.NewTimer(d.untilNext())
+    defer timer.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            log.Printf("daemon stopping")
+            return nil
+        case <-ticker.C:
+            changed, err := d.changed()
+            if err != nil {
+                log.Printf("Failed to check for changes: %v", err)
+                continue
+            }
+            if !changed {
+                continue
+            }
+            if err := d.reload(); err != nil {
+                log.Printf("Failed to reload tasks: %v", err)
+                continue
+            }
+        case <-timer.C:
+            d.fire()
+        }
+
+        if !timer.Stop() {
+            select {
+            case <-timer.C:
+            default:
+            }
+        }
+        timer.Reset(d.untilNext())
+    }
+}
+
+// changed reports whether the schedule needs rebuilding, either because
+// another process wrote to the database or because the day rolled over.
+func (d *daemon) changed() (bool, error) {
+    if time.Now().In(d.loc).Format("2006-01-02") != d.loadedDay {
+        return true, nil
+    }
+    v, err := d.db.DataVersion()
+    if err != nil {
+        return false, err
+    }
+    return v != d.version, nil
+}
+
+func (d *daemon) reload() error {
+    v, err := d.db.DataVersion()
+    if err != nil {
+        return err
+    }
+
+    now := time.Now().In(d.loc)
+    tasks, err := d.db.GetTasksBetween(now, now.AddDate(0, 0, 2))
+    if err != nil {
+        return err
+    }
+
+    if day := now.Format("2006-01-02"); day != d.loadedDay {
+        path, err := autoBackup(d.db, d.backup, d.loadedDay == "")
+        if err != nil {
+            log.Printf("Failed to back up: %v", err)
+        } else if path != "" {
+            log.Printf("backed up to %s", path)
+        }
+    }
+
+    var reminders []reminder
+    for _, task := range tasks {
+        if task.Done {
+            continue
+        }
+        sent, err := d.db.ReminderSent(task.ID, task.StartsAt)
+        if err != nil {
+            return err
+        }
+        if sent {
+            continue
+        }
+        reminders = append(reminders, reminder{
+            taskID:   task.ID,
+            title:    task.Title,
+            startsAt: task.StartsAt,
+            at:       task.StartsAt.Add(-d.lead),
+        })
+    }
+    sort.Slice(reminders, func(i, j int) bool {
+        return reminders[i].at.Before(reminders[j].at)
+    })
+
+    d.reminders = reminders
+    d.version = v
+    d.loadedDay = now.Format("2006-01-02")
+    return nil
+}
+
+func (d *daemon) untilNext() time.Duration {
+    if len(d.reminders) == 0 {
+        return 24 * time.Hour
+    }
+    wait := time.Until(d.reminders[0].at)
+    if wait < 0 {
+        return 0
+    }
+    return wait
+}
+
+// fire sends the reminders that are due. One that fails to send stays queued
+// and is tried again every poll interval until its task starts.
+func (d *daemon) fire() {
+    now := time.Now()
+    var failed []reminder
+    for len(d.reminders) > 0 && !d.reminders[0].at.After(now) {
+        r := d.reminders[0]
+        d.reminders = d.reminders[1:]
+
+        body := fmt.Sprintf("Starts at %s", r.startsAt.In(d.loc).Format("3:04 PM"))
+        if err := notify(r.title, body); err != nil {
+            log.Printf("Failed to send reminder for %q: %v", r.title, err)
+            if now.Before(r.startsAt) {
+                r.at = now.Add(d.retry)
+                if r.at.After(r.startsAt) {
+                    r.at = r.startsAt
+                }
+                failed = append(failed, r)
+            }
+            continue
+        }
+        if err := d.db.MarkReminderSent(r.taskID, r.startsAt); err != nil {
+            log.Printf("Failed to record reminder for %q: %v", r.title, err)
+        }
+    }
+
+    if len(failed) > 0 {
+        d.reminders = append(d.reminders, failed...)
+        sort.Slice(d.reminders, func(i, j int) bool {
+            return d.reminders[i].at.Before(d.reminders[j].at)
+        })
+    }
+}
+
+// systemdQuote quotes path for ExecStart= so a space in it does not split
+// it and a % in it is not read as a specifier.
+func systemdQuote(path string) string {
+    return strings.ReplaceAll(strconv.Quote(path), "%", "%%")
+}
+
+// notify shows a desktop notification. Without notify-send nothing is shown,
+// so that is an error too and the reminder is tried again.
+func notify(title, body string) error {
+    log.Printf("reminder: %s (%s)", title, body)
+    path, err := exec.LookPath("notify-send")
+    if err != nil {
+        return err
+    }
+    return exec.Command(path, "--app-name=scheduler", title, body).Run()
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+)
+
+func TestSystemdUnitQuotesPath(t *testing.T) {
+    unit := fmt.Sprintf(systemdUnit, systemdQuote(`/home/me/My Apps/100% "real"/scheduler`), "5m0s")
+    want := `ExecStart="/home/me/My Apps/100%% \"real\"/scheduler" daemon -lead 5m0s`
+    if !strings.Contains(unit, want+"\n") {
+        t.Errorf("unit does not contain %s:\n%s", want, unit)
+    }
+}
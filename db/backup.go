@@ -0,0 +1,143 @@
+package db
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/mattn/go-sqlite3"
+)
+
+// backupTimeout bounds how long a copy waits for writers in other processes
+// to let go of the database.
+const backupTimeout = 30 * time.Second
+
+// Backup writes a consistent copy of the database to path with SQLite's
+// online backup API, so other processes can keep using it meanwhile.
+func (db *DB) Backup(path string) error {
+    dst, err := sql.Open("sqlite3", path)
+    if err != nil {
+        return err
+    }
+    defer dst.Close()
+    return copyDatabase(dst, db.DB)
+}
+
+// Restore replaces the contents of the database with the database at path.
+// It goes through SQLite rather than the file system so processes that have
+// the database open see the restored data instead of a stale file.
+func (db *DB) Restore(path string) error {
+    src, err := openReadOnly(path)
+    if err != nil {
+        return err
+    }
+    defer src.Close()
+    return copyDatabase(db.DB, src)
+}
+
+// CheckIntegrity reports whether path is an intact scheduler database.
+func CheckIntegrity(path string) error {
+    conn, err := openReadOnly(path)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    rows, err := conn.Query(`PRAGMA integrity_check`)
+    if err != nil {
+        return fmt.Errorf("%s: %v", path, err)
+    }
+    var problems []string
+    for rows.Next() {
+        var msg string
+        if err := rows.Scan(&msg); err != nil {
+            rows.Close()
+            return err
+        }
+        if msg != "ok" {
+            problems = append(problems, msg)
+        }
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("%s: %v", path, err)
+    }
+    if len(problems) > 0 {
+        return fmt.Errorf("%s failed the integrity check: %s", path, strings.Join(problems, "; "))
+    }
+
+    var n int
+    err = conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'`).Scan(&n)
+    if err != nil {
+        return fmt.Errorf("%s: %v", path, err)
+    }
+    if n == 0 {
+        return fmt.Errorf("%s is not a scheduler database", path)
+    }
+    return nil
+}
+
+func openReadOnly(path string) (*sql.DB, error) {
+    u := url.URL{Scheme: "file", Path: path, RawQuery: "mode=ro"}
+    conn, err := sql.Open("sqlite3", u.String())
+    if err != nil {
+        return nil, err
+    }
+    if err := conn.Ping(); err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    return conn, nil
+}
+
+func copyDatabase(dst, src *sql.DB) error {
+    ctx := context.Background()
+    dstConn, err := dst.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer dstConn.Close()
+    srcConn, err := src.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer srcConn.Close()
+
+    return dstConn.Raw(func(d any) error {
+        return srcConn.Raw(func(s any) error {
+            to, ok1 := d.(*sqlite3.SQLiteConn)
+            from, ok2 := s.(*sqlite3.SQLiteConn)
+            if !ok1 || !ok2 {
+                return errors.New("backup needs sqlite3 connections")
+            }
+            b, err := to.Backup("main", from, "main")
+            if err != nil {
+                return err
+            }
+
+            // Step reports false without an error while another process
+            // holds a lock, so keep trying until the deadline.
+            deadline := time.Now().Add(backupTimeout)
+            for {
+                done, err := b.Step(-1)
+                if err != nil {
+                    b.Finish()
+                    return err
+                }
+                if done {
+                    break
+                }
+                if time.Now().After(deadline) {
+                    b.Finish()
+                    return errors.New("database stayed locked by another process")
+                }
+                time.Sleep(100 * time.Millisecond)
+            }
+            return b.Finish()
+        })
+    })
+}
@@ -0,0 +1,51 @@
+package db
+
+// CalDAVLink ties a task to an object in a CalDAV collection. ETag is the
+// remote version and Hash the local one as of the last sync, so either side
+// can tell whether it has changed since.
+type CalDAVLink struct {
+    TaskID     int64
+    Collection string
+    UID        string
+    Href       string
+    ETag       string
+    Hash       string
+}
+
+func (db *DB) CalDAVLinks(collection string) ([]CalDAVLink, error) {
+    rows, err := db.Query(`
+        SELECT task_id, collection, uid, href, etag, hash
+        FROM caldav_links
+        WHERE collection = ?
+    `, collection)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var links []CalDAVLink
+    for rows.Next() {
+        var l CalDAVLink
+        if err := rows.Scan(&l.TaskID, &l.Collection, &l.UID, &l.Href, &l.ETag, &l.Hash); err != nil {
+            return nil, err
+        }
+        links = append(links, l)
+    }
+    return links, rows.Err()
+}
+
+func (db *DB) SaveCalDAVLink(l CalDAVLink) error {
+    _, err := db.Exec(`
+        INSERT OR REPLACE INTO caldav_links (task_id, collection, uid, href, etag, hash, synced_at)
+        VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+    `, l.TaskID, l.Collection, l.UID, l.Href, l.ETag, l.Hash)
+    return err
+}
+
+func (db *DB) DeleteCalDAVLink(taskID int64) error {
+    _, err := db.Exec(`
+        DELETE FROM caldav_links
+        WHERE task_id = ?
+    `, taskID)
+    return err
+}
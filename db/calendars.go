@@ -0,0 +1,106 @@
+package db
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+)
+
+var ErrCalendarNotFound = errors.New("no such calendar")
+
+type Calendar struct {
+    ID     int64
+    Name   string
+    Color  string
+    Hidden bool
+}
+
+func (db *DB) Calendars() ([]Calendar, error) {
+    rows, err := db.Query(`
+        SELECT id, name, color, hidden
+        FROM calendars
+        ORDER BY id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var calendars []Calendar
+    for rows.Next() {
+        var c Calendar
+        if err := rows.Scan(&c.ID, &c.Name, &c.Color, &c.Hidden); err != nil {
+            return nil, err
+        }
+        calendars = append(calendars, c)
+    }
+    return calendars, rows.Err()
+}
+
+func (db *DB) CalendarByName(name string) (Calendar, error) {
+    var c Calendar
+    err := db.QueryRow(`
+        SELECT id, name, color, hidden
+        FROM calendars
+        WHERE name = ? COLLATE NOCASE
+    `, name).Scan(&c.ID, &c.Name, &c.Color, &c.Hidden)
+    if err == sql.ErrNoRows {
+        return c, fmt.Errorf("%w: %q", ErrCalendarNotFound, name)
+    }
+    return c, err
+}
+
+func (db *DB) CreateCalendar(name, color string) (Calendar, error) {
+    res, err := db.Exec(`
+        INSERT INTO calendars (name, color)
+        VALUES (?, ?)
+    `, name, color)
+    if err != nil {
+        return Calendar{}, err
+    }
+    id, err := res.LastInsertId()
+    return Calendar{ID: id, Name: name, Color: color}, err
+}
+
+func (db *DB) SetCalendarColor(id int64, color string) error {
+    _, err := db.Exec(`
+        UPDATE calendars
+        SET color = ?
+        WHERE id = ?
+    `, color, id)
+    return err
+}
+
+// SetCalendarHidden stores whether the schedule shows the calendar's tasks.
+func (db *DB) SetCalendarHidden(id int64, hidden bool) error {
+    _, err := db.Exec(`
+        UPDATE calendars
+        SET hidden = ?
+        WHERE id = ?
+    `, hidden, id)
+    return err
+}
+
+// GetCalendarTasks returns every task in a calendar, whenever it starts.
+func (db *DB) GetCalendarTasks(calendarID int64) ([]Task, error) {
+    rows, err := db.Query(`
+        SELECT `+taskColumns+`
+        FROM tasks
+        WHERE calendar_id = ?
+        ORDER BY starts_at
+    `, calendarID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var tasks []Task
+    for rows.Next() {
+        t, err := scanTask(rows)
+        if err != nil {
+            return nil, err
+        }
+        tasks = append(tasks, t)
+    }
+    return tasks, rows.Err()
+}
@@ -2,6 +2,7 @@ package db
 
 import (
     "database/sql"
+    "errors"
     "fmt"
     "os"
     "path/filepath"
@@ -14,13 +15,20 @@ type DB struct {
     *sql.DB
 }
 
+var ErrTaskNotFound = errors.New("no such task")
+
 type Task struct {
     ID        int64
+    CalendarID int64
     Date      string
-    TimeSlot  int
+    StartMinute int
+    StartsAt  time.Time
+    TZ        string
     Title     string
     Duration  int
     Done      bool
+    Pomodoros int
+    Tracked   time.Duration
     CreatedAt time.Time
 }
 
@@ -30,13 +38,14 @@ func NewDB() (*DB, error) {
         return nil, fmt.Errorf("failed to get home directory: %v", err)
     }
     
-    dbDir := filepath.Join(homeDir, ".scheduler")
-    if err := os.MkdirAll(dbDir, 0755); err != nil {
+    return Open(filepath.Join(homeDir, ".scheduler", "scheduler.db"))
+}
+
+func Open(dbPath string) (*DB, error) {
+    if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
         return nil, fmt.Errorf("failed to create database directory: %v", err)
     }
     
-    dbPath := filepath.Join(dbDir, "scheduler.db")
-    
     db, err := sql.Open("sqlite3", dbPath)
     if err != nil {
         return nil, fmt.Errorf("failed to open database: %v", err)
@@ -66,32 +75,170 @@ func initSchema(db *sql.DB) error {
         created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
     CREATE INDEX IF NOT EXISTS idx_tasks_date ON tasks(date);
+    CREATE TABLE IF NOT EXISTS reminders_sent (
+        task_id INTEGER NOT NULL,
+        starts_at TIMESTAMP NOT NULL,
+        sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (task_id, starts_at)
+    );
+    CREATE TABLE IF NOT EXISTS pomodoros (
+        id INTEGER PRIMARY KEY,
+        task_id INTEGER NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        ended_at TIMESTAMP NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_pomodoros_task ON pomodoros(task_id);
+    CREATE TABLE IF NOT EXISTS time_entries (
+        id INTEGER PRIMARY KEY,
+        task_id INTEGER NOT NULL,
+        started_at TIMESTAMP NOT NULL,
+        stopped_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries(task_id);
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running
+        ON time_entries((stopped_at IS NULL)) WHERE stopped_at IS NULL;
     `
     
-    _, err := db.Exec(schema)
-    return err
+    if _, err := db.Exec(schema); err != nil {
+        return err
+    }
+    return migrate(db)
 }
 
-func (db *DB) SaveTask(date time.Time, timeSlot int, title string, duration int) error {
-    dateStr := date.Format("2006-01-02")
-    
-    _, err := db.Exec(`
-        INSERT INTO tasks (date, time_slot, title, duration, done)
-        VALUES (?, ?, ?, ?, ?)
-    `, dateStr, timeSlot, title, duration, false)
+// execer is what task writes need, so they can run alone or as part of a
+// transaction.
+type execer interface {
+    Exec(query string, args ...any) (sql.Result, error)
+}
+
+// SaveTask stores a task in calendarID starting at startsAt. The location of
+// startsAt is kept with the task so its local date and time can be recovered
+// later.
+func (db *DB) SaveTask(calendarID int64, startsAt time.Time, title string, duration int) (int64, error) {
+    return saveTask(db, calendarID, startsAt, title, duration, false)
+}
+
+// CreateTask stores t as a new task, Done included, and returns its ID.
+func (db *DB) CreateTask(t Task) (int64, error) {
+    return saveTask(db, t.CalendarID, t.StartsAt, t.Title, t.Duration, t.Done)
+}
+
+func saveTask(ex execer, calendarID int64, startsAt time.Time, title string, duration int, done bool) (int64, error) {
+    startMinute := startsAt.Hour()*60 + startsAt.Minute()
     
-    return err
+    res, err := ex.Exec(`
+        INSERT INTO tasks (calendar_id, date, time_slot, start_minute, starts_at, tz, title, duration, done)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, calendarID, startsAt.Format("2006-01-02"), startMinute/legacySlotMinutes, startMinute,
+        startsAt.UTC(), ZoneName(startsAt.Location()), title, duration, done)
+    if err != nil {
+        return 0, err
+    }
+    return res.LastInsertId()
+}
+
+// UpdateTask writes every editable field of t back to its row.
+func (db *DB) UpdateTask(t Task) error {
+    return updateTask(db, t)
+}
+
+func updateTask(ex execer, t Task) error {
+    startMinute := t.StartsAt.Hour()*60 + t.StartsAt.Minute()
+
+    res, err := ex.Exec(`
+        UPDATE tasks
+        SET calendar_id = ?, date = ?, time_slot = ?, start_minute = ?, starts_at = ?, tz = ?,
+            title = ?, duration = ?, done = ?
+        WHERE id = ?
+    `, t.CalendarID, t.StartsAt.Format("2006-01-02"), startMinute/legacySlotMinutes, startMinute,
+        t.StartsAt.UTC(), ZoneName(t.StartsAt.Location()), t.Title, t.Duration, t.Done, t.ID)
+    if err != nil {
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrTaskNotFound
+    }
+    return nil
 }
 
+// ImportTasks writes updates and creates new tasks in one transaction and
+// returns the new tasks' IDs in order. beforeCommit, when given, runs with
+// those IDs before the transaction commits, and an error from it undoes the
+// whole import.
+func (db *DB) ImportTasks(updates, creates []Task, beforeCommit func(ids []int64) error) ([]int64, error) {
+    tx, err := db.Begin()
+    if err != nil {
+        return nil, err
+    }
+    defer tx.Rollback()
+
+    for _, t := range updates {
+        if err := updateTask(tx, t); err != nil {
+            return nil, fmt.Errorf("task %d: %v", t.ID, err)
+        }
+    }
+    ids := make([]int64, len(creates))
+    for i, t := range creates {
+        id, err := saveTask(tx, t.CalendarID, t.StartsAt, t.Title, t.Duration, t.Done)
+        if err != nil {
+            return nil, err
+        }
+        ids[i] = id
+    }
+
+    if beforeCommit != nil {
+        if err := beforeCommit(ids); err != nil {
+            return nil, err
+        }
+    }
+    return ids, tx.Commit()
+}
+
+const taskColumns = `
+    id, calendar_id, date, start_minute, starts_at, tz, title, duration, done,
+    (SELECT COUNT(*) FROM pomodoros WHERE task_id = tasks.id),
+    (SELECT COALESCE(SUM(julianday(stopped_at) - julianday(started_at)), 0) * 86400
+        FROM time_entries WHERE task_id = tasks.id AND stopped_at IS NOT NULL),
+    created_at`
+
+type scanner interface {
+    Scan(dest ...any) error
+}
+
+func scanTask(row scanner) (Task, error) {
+    var t Task
+    var tracked float64
+    err := row.Scan(&t.ID, &t.CalendarID, &t.Date, &t.StartMinute, &t.StartsAt, &t.TZ, &t.Title, &t.Duration, &t.Done, &t.Pomodoros, &tracked, &t.CreatedAt)
+    if err != nil {
+        return t, err
+    }
+    t.StartsAt = t.StartsAt.In(loadZone(t.TZ))
+    t.Tracked = time.Duration(tracked * float64(time.Second)).Round(time.Second)
+    return t, nil
+}
+
+func (db *DB) GetTask(id int64) (Task, error) {
+    t, err := scanTask(db.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, id))
+    if err == sql.ErrNoRows {
+        return t, ErrTaskNotFound
+    }
+    return t, err
+}
+
+// GetTasksForDate returns the tasks starting on date's day in date's location.
 func (db *DB) GetTasksForDate(date time.Time) ([]Task, error) {
-    dateStr := date.Format("2006-01-02")
-    
+    from := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+    return db.GetTasksBetween(from, from.AddDate(0, 0, 1))
+}
+
+// GetTasksBetween returns the tasks starting at or after from and before to.
+func (db *DB) GetTasksBetween(from, to time.Time) ([]Task, error) {
     rows, err := db.Query(`
-        SELECT id, time_slot, title, duration, done, created_at
+        SELECT `+taskColumns+`
         FROM tasks
-        WHERE date = ?
-        ORDER BY time_slot
-    `, dateStr)
+        WHERE starts_at >= ? AND starts_at < ?
+        ORDER BY starts_at
+    `, from.UTC().Truncate(time.Second), to.UTC().Truncate(time.Second))
     if err != nil {
         return nil, err
     }
@@ -99,12 +246,10 @@ func (db *DB) GetTasksForDate(date time.Time) ([]Task, error) {
     
     var tasks []Task
     for rows.Next() {
-        var t Task
-        err := rows.Scan(&t.ID, &t.TimeSlot, &t.Title, &t.Duration, &t.Done, &t.CreatedAt)
+        t, err := scanTask(rows)
         if err != nil {
             return nil, err
         }
-        t.Date = dateStr
         tasks = append(tasks, t)
     }
     
@@ -122,8 +267,30 @@ func (db *DB) UpdateTaskDone(taskID int64, done bool) error {
 
 func (db *DB) DeleteTask(taskID int64) error {
     _, err := db.Exec(`
+        DELETE FROM pomodoros
+        WHERE task_id = ?
+    `, taskID)
+    if err != nil {
+        return err
+    }
+
+    _, err = db.Exec(`
+        DELETE FROM time_entries
+        WHERE task_id = ?
+    `, taskID)
+    if err != nil {
+        return err
+    }
+
+    res, err := db.Exec(`
         DELETE FROM tasks
         WHERE id = ?
     `, taskID)
-    return err
+    if err != nil {
+        return err
+    }
+    if n, err := res.RowsAffected(); err == nil && n == 0 {
+        return ErrTaskNotFound
+    }
+    return nil
 }
@@ -0,0 +1,59 @@
+package db
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/mattn/go-sqlite3"
+)
+
+// historyFields are the task columns whose changes are kept in task_history.
+var historyFields = []string{"title", "starts_at", "duration", "done", "calendar_id"}
+
+// HistoryEntry is one change to a task. Field is a column name, "created"
+// with New holding the start time the task was created with, or "deleted"
+// with Old holding the title the task had.
+type HistoryEntry struct {
+    Field     string
+    Old       string
+    New       string
+    ChangedAt time.Time
+}
+
+// TaskHistory returns the changes made to a task, oldest first. History of
+// an earlier, deleted task with the same ID is left out.
+func (db *DB) TaskHistory(taskID int64) ([]HistoryEntry, error) {
+    rows, err := db.Query(`
+        SELECT field, COALESCE(old_value, ''), COALESCE(new_value, ''), changed_at
+        FROM task_history
+        WHERE task_id = ? AND id > COALESCE((
+            SELECT MAX(id) FROM task_history WHERE task_id = ? AND field = 'deleted'
+        ), 0)
+        ORDER BY id
+    `, taskID, taskID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var entries []HistoryEntry
+    for rows.Next() {
+        var e HistoryEntry
+        if err := rows.Scan(&e.Field, &e.Old, &e.New, &e.ChangedAt); err != nil {
+            return nil, err
+        }
+        entries = append(entries, e)
+    }
+    return entries, rows.Err()
+}
+
+// ParseTimestamp reads a time as the driver stores it, which is how start
+// times appear in a task's history.
+func ParseTimestamp(s string) (time.Time, error) {
+    for _, layout := range sqlite3.SQLiteTimestampFormats {
+        if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+            return t, nil
+        }
+    }
+    return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+    "database/sql"
+    "fmt"
+    "time"
+)
+
+// legacySlotMinutes is the slot length time_slot values were written in
+// before tasks recorded their start as minutes past midnight. The column is
+// still kept in step so older binaries can read the database.
+const legacySlotMinutes = 30
+
+// migrations run in order on top of the base schema; PRAGMA user_version
+// records how many have been applied.
+var migrations = []func(tx *sql.Tx) error{
+    func(tx *sql.Tx) error {
+        if _, err := tx.Exec(`ALTER TABLE tasks ADD COLUMN start_minute INTEGER NOT NULL DEFAULT 0`); err != nil {
+            return err
+        }
+        _, err := tx.Exec(`UPDATE tasks SET start_minute = time_slot * ?`, legacySlotMinutes)
+        return err
+    },
+    addTaskTimezones,
+    addCalendars,
+    addCalDAVLinks,
+    addChangeLog,
+    addTaskHistory,
+    keepDeletedHistory,
+}
+
+// addTaskTimezones gives every task an absolute start time and the zone it
+// was planned in. Rows written before this existed were in the machine's
+// local zone, so that is what they are assigned.
+func addTaskTimezones(tx *sql.Tx) error {
+    stmts := []string{
+        `ALTER TABLE tasks ADD COLUMN starts_at TIMESTAMP`,
+        `ALTER TABLE tasks ADD COLUMN tz TEXT NOT NULL DEFAULT 'UTC'`,
+        `CREATE INDEX IF NOT EXISTS idx_tasks_starts_at ON tasks(starts_at)`,
+    }
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt); err != nil {
+            return err
+        }
+    }
+
+    rows, err := tx.Query(`SELECT id, date, start_minute FROM tasks`)
+    if err != nil {
+        return err
+    }
+    type row struct {
+        id       int64
+        startsAt time.Time
+    }
+    var pending []row
+    for rows.Next() {
+        var id int64
+        var date string
+        var minute int
+        if err := rows.Scan(&id, &date, &minute); err != nil {
+            rows.Close()
+            return err
+        }
+        day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+        if err != nil {
+            rows.Close()
+            return fmt.Errorf("task %d: %v", id, err)
+        }
+        startsAt := time.Date(day.Year(), day.Month(), day.Day(), 0, minute, 0, 0, time.Local)
+        pending = append(pending, row{id: id, startsAt: startsAt})
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return err
+    }
+
+    zone := ZoneName(time.Local)
+    for _, r := range pending {
+        _, err := tx.Exec(`UPDATE tasks SET starts_at = ?, tz = ? WHERE id = ?`, r.startsAt.UTC(), zone, r.id)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func migrate(db *sql.DB) error {
+    var version int
+    if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+        return err
+    }
+
+    for i := version; i < len(migrations); i++ {
+        tx, err := db.Begin()
+        if err != nil {
+            return err
+        }
+        if err := migrations[i](tx); err != nil {
+            tx.Rollback()
+            return fmt.Errorf("migration %d: %v", i+1, err)
+        }
+        if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+            tx.Rollback()
+            return fmt.Errorf("migration %d: %v", i+1, err)
+        }
+        if err := tx.Commit(); err != nil {
+            return fmt.Errorf("migration %d: %v", i+1, err)
+        }
+    }
+
+    return nil
+}
+
+// addCalendars puts every existing task into a default calendar so schedules
+// can be split up from here on.
+func addCalendars(tx *sql.Tx) error {
+    stmts := []string{
+        `CREATE TABLE calendars (
+            id INTEGER PRIMARY KEY,
+            name TEXT NOT NULL UNIQUE,
+            color TEXT NOT NULL DEFAULT '',
+            hidden BOOLEAN NOT NULL DEFAULT 0,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `INSERT INTO calendars (id, name) VALUES (1, 'Default')`,
+        `ALTER TABLE tasks ADD COLUMN calendar_id INTEGER NOT NULL DEFAULT 1`,
+        `CREATE INDEX IF NOT EXISTS idx_tasks_calendar ON tasks(calendar_id)`,
+    }
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// addCalDAVLinks records which remote object each synced task belongs to and
+// what both sides looked like at the last sync.
+func addCalDAVLinks(tx *sql.Tx) error {
+    _, err := tx.Exec(`
+        CREATE TABLE caldav_links (
+            task_id INTEGER PRIMARY KEY,
+            collection TEXT NOT NULL,
+            uid TEXT NOT NULL,
+            href TEXT NOT NULL,
+            etag TEXT NOT NULL,
+            hash TEXT NOT NULL,
+            synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE (collection, uid)
+        )
+    `)
+    return err
+}
+
+// addChangeLog gives tasks an identity that is the same on every machine and
+// starts logging their changes so databases can be merged. Existing tasks get
+// a uid built from their row, so copies of one database agree on it, and an
+// insert at clock 0 standing in for their history.
+func addChangeLog(tx *sql.Tx) error {
+    stmts := []string{
+        `ALTER TABLE tasks ADD COLUMN uid TEXT`,
+        `UPDATE tasks SET uid = 'legacy-' || id || '-' || COALESCE(strftime('%s', created_at), '')`,
+        `CREATE UNIQUE INDEX idx_tasks_uid ON tasks(uid)`,
+        `CREATE TABLE sync_state (
+            device TEXT NOT NULL,
+            clock INTEGER NOT NULL,
+            applying BOOLEAN NOT NULL DEFAULT 0
+        )`,
+        `INSERT INTO sync_state (device, clock) VALUES (lower(hex(randomblob(8))), 0)`,
+        `CREATE TABLE changes (
+            id TEXT PRIMARY KEY,
+            device TEXT NOT NULL,
+            clock INTEGER NOT NULL,
+            task_uid TEXT NOT NULL,
+            op TEXT NOT NULL,
+            data TEXT,
+            recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `CREATE INDEX idx_changes_task ON changes(task_uid)`,
+        `INSERT INTO changes (id, device, clock, task_uid, op, data)
+            SELECT lower(hex(randomblob(16))), s.device, 0, t.uid, 'insert', ` + taskSnapshot + `
+            FROM sync_state s, tasks t`,
+        `CREATE TRIGGER tasks_log_insert AFTER INSERT ON tasks
+        WHEN (SELECT applying FROM sync_state) = 0
+        BEGIN
+            UPDATE tasks SET uid = lower(hex(randomblob(16))) WHERE id = NEW.id AND uid IS NULL;
+            UPDATE sync_state SET clock = clock + 1;
+            INSERT INTO changes (id, device, clock, task_uid, op, data)
+                SELECT lower(hex(randomblob(16))), s.device, s.clock, t.uid, 'insert', ` + taskSnapshot + `
+                FROM sync_state s, tasks t WHERE t.id = NEW.id;
+        END`,
+        // Filling in the uid above is an update too; OLD.uid being NULL
+        // keeps it out of the log.
+        `CREATE TRIGGER tasks_log_update AFTER UPDATE ON tasks
+        WHEN (SELECT applying FROM sync_state) = 0 AND OLD.uid IS NOT NULL AND (` + changedColumns() + `)
+        BEGIN
+            UPDATE sync_state SET clock = clock + 1;
+            INSERT INTO changes (id, device, clock, task_uid, op, data)
+                SELECT lower(hex(randomblob(16))), s.device, s.clock, t.uid, 'update', ` + taskSnapshot + `
+                FROM sync_state s, tasks t WHERE t.id = NEW.id;
+        END`,
+        `CREATE TRIGGER tasks_log_delete AFTER DELETE ON tasks
+        WHEN (SELECT applying FROM sync_state) = 0 AND OLD.uid IS NOT NULL
+        BEGIN
+            UPDATE sync_state SET clock = clock + 1;
+            INSERT INTO changes (id, device, clock, task_uid, op)
+                SELECT lower(hex(randomblob(16))), device, clock, OLD.uid, 'delete'
+                FROM sync_state;
+        END`,
+    }
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// addTaskHistory keeps an audit trail of every task, one row per field that
+// changed. Tasks that already exist start their history with their creation.
+func addTaskHistory(tx *sql.Tx) error {
+    stmts := []string{
+        `CREATE TABLE task_history (
+            id INTEGER PRIMARY KEY,
+            task_id INTEGER NOT NULL,
+            field TEXT NOT NULL,
+            old_value TEXT,
+            new_value TEXT,
+            changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+        `CREATE INDEX idx_task_history_task ON task_history(task_id)`,
+        `INSERT INTO task_history (task_id, field, new_value, changed_at)
+            SELECT id, 'created', starts_at, COALESCE(created_at, CURRENT_TIMESTAMP) FROM tasks`,
+        `CREATE TRIGGER tasks_history_insert AFTER INSERT ON tasks
+        BEGIN
+            INSERT INTO task_history (task_id, field, new_value) VALUES (NEW.id, 'created', NEW.starts_at);
+        END`,
+        `CREATE TRIGGER tasks_history_delete AFTER DELETE ON tasks
+        BEGIN
+            DELETE FROM task_history WHERE task_id = OLD.id;
+        END`,
+    }
+
+    update := "CREATE TRIGGER tasks_history_update AFTER UPDATE ON tasks\nBEGIN\n"
+    for _, field := range historyFields {
+        update += fmt.Sprintf(`
+            INSERT INTO task_history (task_id, field, old_value, new_value)
+                SELECT NEW.id, '%[1]s', OLD.%[1]s, NEW.%[1]s WHERE OLD.%[1]s IS NOT NEW.%[1]s;`, field)
+    }
+    stmts = append(stmts, update+"\nEND")
+
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// keepDeletedHistory stops deleting a task's history along with the task and
+// records the deletion instead, so the audit trail outlives the task. Task
+// IDs can be reused, so a task's history starts after the last deletion of
+// its ID.
+func keepDeletedHistory(tx *sql.Tx) error {
+    stmts := []string{
+        `DROP TRIGGER tasks_history_delete`,
+        `CREATE TRIGGER tasks_history_delete AFTER DELETE ON tasks
+        BEGIN
+            INSERT INTO task_history (task_id, field, old_value) VALUES (OLD.id, 'deleted', OLD.title);
+        END`,
+    }
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt); err != nil {
+            return err
+        }
+    }
+    return nil
+}
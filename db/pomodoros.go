@@ -0,0 +1,13 @@
+package db
+
+import (
+    "time"
+)
+
+func (db *DB) LogPomodoro(taskID int64, startedAt, endedAt time.Time) error {
+    _, err := db.Exec(`
+        INSERT INTO pomodoros (task_id, started_at, ended_at)
+        VALUES (?, ?, ?)
+    `, taskID, startedAt, endedAt)
+    return err
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+    "time"
+)
+
+// ReminderSent reports whether a reminder for the task starting at startsAt
+// has already been delivered. Keying on the start time means a rescheduled
+// task gets reminded again.
+func (db *DB) ReminderSent(taskID int64, startsAt time.Time) (bool, error) {
+    var n int
+    err := db.QueryRow(`
+        SELECT COUNT(*)
+        FROM reminders_sent
+        WHERE task_id = ? AND starts_at = ?
+    `, taskID, startsAt.UTC()).Scan(&n)
+    return n > 0, err
+}
+
+func (db *DB) MarkReminderSent(taskID int64, startsAt time.Time) error {
+    _, err := db.Exec(`
+        INSERT OR IGNORE INTO reminders_sent (task_id, starts_at)
+        VALUES (?, ?)
+    `, taskID, startsAt.UTC())
+    return err
+}
+
+// DataVersion changes whenever another connection commits to the database,
+// which lets long-running processes notice edits made elsewhere.
+func (db *DB) DataVersion() (int64, error) {
+    var v int64
+    err := db.QueryRow(`PRAGMA data_version`).Scan(&v)
+    return v, err
+}
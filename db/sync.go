@@ -0,0 +1,231 @@
+package db
+
+import (
+    "database/sql"
+    "fmt"
+    "strings"
+)
+
+// syncedColumns are the task columns carried in the change log, besides the
+// calendar, which travels by name since ids differ between databases.
+var syncedColumns = []string{
+    "date", "time_slot", "start_minute", "starts_at", "tz", "title", "duration", "done",
+}
+
+// taskSnapshot is the JSON a change records for the task row aliased t.
+var taskSnapshot = func() string {
+    parts := []string{"'calendar', (SELECT name FROM calendars WHERE id = t.calendar_id)"}
+    for _, c := range syncedColumns {
+        parts = append(parts, fmt.Sprintf("'%s', t.%s", c, c))
+    }
+    return "json_object(" + strings.Join(parts, ", ") + ")"
+}()
+
+func changedColumns() string {
+    parts := []string{"OLD.calendar_id IS NOT NEW.calendar_id"}
+    for _, c := range syncedColumns {
+        parts = append(parts, fmt.Sprintf("OLD.%s IS NOT NEW.%s", c, c))
+    }
+    return strings.Join(parts, " OR ")
+}
+
+// Change is one entry of the change log. Clock is a Lamport clock: every
+// local change takes the next value, and merging moves it past everything
+// received, so (Clock, Device, ID) orders changes the same way everywhere.
+type Change struct {
+    ID      string
+    Device  string
+    Clock   int64
+    TaskUID string
+    Op      string
+    Data    sql.NullString
+}
+
+// Device returns the id this database logs its own changes under.
+func (db *DB) Device() (string, error) {
+    var device string
+    err := db.QueryRow(`SELECT device FROM sync_state`).Scan(&device)
+    return device, err
+}
+
+// ResetDevice gives the database a new device id, for when it turns out to
+// be a copy of another one.
+func (db *DB) ResetDevice() (string, error) {
+    if _, err := db.Exec(`UPDATE sync_state SET device = lower(hex(randomblob(8)))`); err != nil {
+        return "", err
+    }
+    return db.Device()
+}
+
+// MissingChanges returns the changes logged in other that db has not seen.
+func (db *DB) MissingChanges(other *DB) ([]Change, error) {
+    rows, err := db.Query(`SELECT id FROM changes`)
+    if err != nil {
+        return nil, err
+    }
+    known := make(map[string]bool)
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            rows.Close()
+            return nil, err
+        }
+        known[id] = true
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, err
+    }
+
+    rows, err = other.Query(`
+        SELECT id, device, clock, task_uid, op, data
+        FROM changes
+        ORDER BY clock, device, id
+    `)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var missing []Change
+    for rows.Next() {
+        var c Change
+        if err := rows.Scan(&c.ID, &c.Device, &c.Clock, &c.TaskUID, &c.Op, &c.Data); err != nil {
+            return nil, err
+        }
+        if !known[c.ID] {
+            missing = append(missing, c)
+        }
+    }
+    return missing, rows.Err()
+}
+
+// ApplyChanges adds changes to the log and brings every task they touch to
+// the state of its latest change, whichever database that came from. It
+// returns how many tasks were written.
+func (db *DB) ApplyChanges(changes []Change) (int, error) {
+    if len(changes) == 0 {
+        return 0, nil
+    }
+    tx, err := db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    // The rows written here are already in the log.
+    if _, err := tx.Exec(`UPDATE sync_state SET applying = 1`); err != nil {
+        return 0, err
+    }
+
+    var clock int64
+    var uids []string
+    incoming := make(map[string]bool, len(changes))
+    touched := make(map[string]bool)
+    for _, c := range changes {
+        incoming[c.ID] = true
+        _, err := tx.Exec(`
+            INSERT OR IGNORE INTO changes (id, device, clock, task_uid, op, data)
+            VALUES (?, ?, ?, ?, ?, ?)
+        `, c.ID, c.Device, c.Clock, c.TaskUID, c.Op, c.Data)
+        if err != nil {
+            return 0, err
+        }
+        if c.Clock > clock {
+            clock = c.Clock
+        }
+        if !touched[c.TaskUID] {
+            touched[c.TaskUID] = true
+            uids = append(uids, c.TaskUID)
+        }
+    }
+
+    written := 0
+    for _, uid := range uids {
+        var latest Change
+        err := tx.QueryRow(`
+            SELECT id, op, data
+            FROM changes
+            WHERE task_uid = ?
+            ORDER BY clock DESC, device DESC, id DESC
+            LIMIT 1
+        `, uid).Scan(&latest.ID, &latest.Op, &latest.Data)
+        if err != nil {
+            return 0, err
+        }
+        // The latest change may be one this database made itself, in which
+        // case the task already looks like it.
+        if !incoming[latest.ID] {
+            continue
+        }
+
+        if latest.Op == "delete" {
+            err = deleteTaskByUID(tx, uid)
+        } else {
+            err = writeTask(tx, uid, latest.Data.String)
+        }
+        if err != nil {
+            return 0, fmt.Errorf("task %s: %v", uid, err)
+        }
+        written++
+    }
+
+    _, err = tx.Exec(`UPDATE sync_state SET applying = 0, clock = MAX(clock, ?)`, clock)
+    if err != nil {
+        return 0, err
+    }
+    return written, tx.Commit()
+}
+
+// writeTask creates or overwrites the task uid from a change's snapshot.
+func writeTask(tx *sql.Tx, uid, data string) error {
+    var name string
+    if err := tx.QueryRow(`SELECT COALESCE(json_extract(?, '$.calendar'), 'Default')`, data).Scan(&name); err != nil {
+        return err
+    }
+    if _, err := tx.Exec(`INSERT OR IGNORE INTO calendars (name) VALUES (?)`, name); err != nil {
+        return err
+    }
+    var calendarID int64
+    if err := tx.QueryRow(`SELECT id FROM calendars WHERE name = ?`, name).Scan(&calendarID); err != nil {
+        return err
+    }
+
+    values := make([]string, len(syncedColumns))
+    for i, c := range syncedColumns {
+        values[i] = fmt.Sprintf("json_extract(j, '$.%s')", c)
+    }
+    columns := strings.Join(syncedColumns, ", ")
+    from := `(SELECT ? AS j)`
+
+    var id int64
+    err := tx.QueryRow(`SELECT id FROM tasks WHERE uid = ?`, uid).Scan(&id)
+    switch {
+    case err == sql.ErrNoRows:
+        _, err = tx.Exec(`
+            INSERT INTO tasks (uid, calendar_id, `+columns+`)
+            SELECT ?, ?, `+strings.Join(values, ", ")+` FROM `+from,
+            uid, calendarID, data)
+    case err == nil:
+        _, err = tx.Exec(`
+            UPDATE tasks
+            SET calendar_id = ?, (`+columns+`) = (SELECT `+strings.Join(values, ", ")+` FROM `+from+`)
+            WHERE id = ?
+        `, calendarID, data, id)
+    }
+    return err
+}
+
+func deleteTaskByUID(tx *sql.Tx, uid string) error {
+    stmts := []string{
+        `DELETE FROM pomodoros WHERE task_id IN (SELECT id FROM tasks WHERE uid = ?)`,
+        `DELETE FROM time_entries WHERE task_id IN (SELECT id FROM tasks WHERE uid = ?)`,
+        `DELETE FROM tasks WHERE uid = ?`,
+    }
+    for _, stmt := range stmts {
+        if _, err := tx.Exec(stmt, uid); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+    "strings"
+    "unicode"
+    "unicode/utf8"
+)
+
+// Tags returns the tags in the task title, lower-cased and without
+// duplicates, in the order they first appear. #hashtags are returned without
+// the #; todo.txt style +project and @context tags keep their sign so the two
+// kinds stay apart.
+func (t Task) Tags() []string {
+    var tags []string
+    seen := make(map[string]bool)
+    for _, word := range strings.Fields(t.Title) {
+        if len(word) < 2 {
+            continue
+        }
+        var tag string
+        switch word[0] {
+        case '#':
+            tag = word[1:]
+        case '+', '@':
+            // Only words like +garden or @phone, not +1 or a lone @.
+            if r, _ := utf8.DecodeRuneInString(word[1:]); !unicode.IsLetter(r) {
+                continue
+            }
+            tag = word
+        default:
+            continue
+        }
+        tag = strings.ToLower(strings.TrimRight(tag, ".,;:!?"))
+        if tag == "" || tag == "+" || tag == "@" || seen[tag] {
+            continue
+        }
+        seen[tag] = true
+        tags = append(tags, tag)
+    }
+    return tags
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+)
+
+var ErrTimerRunning = errors.New("a timer is already running")
+
+type TimeEntry struct {
+    ID        int64
+    TaskID    int64
+    TaskTitle string
+    StartedAt time.Time
+    StoppedAt *time.Time
+}
+
+// StartTimer opens a time entry for the task. Only one entry may be running
+// at a time across all processes sharing the database; the partial unique
+// index on time_entries enforces this even if two starts race.
+func (db *DB) StartTimer(taskID int64, at time.Time) error {
+    running, err := db.RunningTimer()
+    if err != nil {
+        return err
+    }
+    if running != nil {
+        return fmt.Errorf("%w: %s", ErrTimerRunning, running.TaskTitle)
+    }
+
+    _, err = db.Exec(`
+        INSERT INTO time_entries (task_id, started_at)
+        VALUES (?, ?)
+    `, taskID, at)
+    if err != nil {
+        if running, _ := db.RunningTimer(); running != nil {
+            return fmt.Errorf("%w: %s", ErrTimerRunning, running.TaskTitle)
+        }
+        return err
+    }
+    return nil
+}
+
+func (db *DB) StopTimer(at time.Time) error {
+    _, err := db.Exec(`
+        UPDATE time_entries
+        SET stopped_at = ?
+        WHERE stopped_at IS NULL
+    `, at)
+    return err
+}
+
+func (db *DB) RunningTimer() (*TimeEntry, error) {
+    var e TimeEntry
+    err := db.QueryRow(`
+        SELECT time_entries.id, task_id, COALESCE(tasks.title, ''), started_at
+        FROM time_entries
+        LEFT JOIN tasks ON tasks.id = time_entries.task_id
+        WHERE stopped_at IS NULL
+    `).Scan(&e.ID, &e.TaskID, &e.TaskTitle, &e.StartedAt)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &e, nil
+}
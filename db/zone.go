@@ -0,0 +1,49 @@
+package db
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// ZoneName returns the IANA name of loc so it can be stored and loaded again
+// later. time.Local only calls itself "Local", so its real name is looked up
+// from $TZ, the /etc/localtime symlink or /etc/timezone. When none of them
+// names it, as where /etc/localtime is a copied file, "Local" is stored so
+// the task follows the local zone wherever it is loaded rather than a wrong
+// name.
+func ZoneName(loc *time.Location) string {
+    if loc != time.Local {
+        return loc.String()
+    }
+
+    if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" {
+        if _, err := time.LoadLocation(tz); err == nil {
+            return tz
+        }
+    }
+    if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+        if _, name, ok := strings.Cut(target, "zoneinfo/"); ok {
+            if _, err := time.LoadLocation(name); err == nil {
+                return name
+            }
+        }
+    }
+    if data, err := os.ReadFile("/etc/timezone"); err == nil {
+        if name := strings.TrimSpace(string(data)); name != "" {
+            if _, err := time.LoadLocation(name); err == nil {
+                return name
+            }
+        }
+    }
+    return "Local"
+}
+
+func loadZone(name string) *time.Location {
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return time.UTC
+    }
+    return loc
+}
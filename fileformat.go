@@ -0,0 +1,221 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+// dayRange is the -date and -days flags of commands that work on a run of
+// days.
+type dayRange struct {
+    date string
+    days int
+}
+
+func (r *dayRange) register(fs *flag.FlagSet) {
+    fs.StringVar(&r.date, "date", "today", "first day, as YYYY-MM-DD or anything the go-to prompt accepts")
+    fs.IntVar(&r.days, "days", 1, "number of days from -date")
+}
+
+// first returns the first day of the range in loc.
+func (r dayRange) first(loc *time.Location) (time.Time, error) {
+    if r.days < 1 {
+        return time.Time{}, fmt.Errorf("-days: %d must be at least 1", r.days)
+    }
+    first, err := parseGotoDate(r.date, time.Now().In(loc))
+    if err != nil {
+        return time.Time{}, fmt.Errorf("-date: %v", err)
+    }
+    return first, nil
+}
+
+// fileEntry is a task as written in an exported file.
+type fileEntry interface {
+    // taskID is the task the entry was exported from, or 0 for one added to
+    // the file.
+    taskID() int64
+    // task applies the entry on top of t, which is empty for a new task.
+    task(t db.Task, s settings) (db.Task, error)
+    // where names the entry's place in the file for error messages.
+    where() string
+}
+
+type importResult struct {
+    created, updated, unchanged, skipped int
+}
+
+// applyImport writes entries to the database. Entries with a task ID update
+// that task; the others become tasks in calendar. Every entry is checked
+// before anything is written, and the writes happen in one transaction.
+// When tasks are created, save is called with their IDs by position in
+// entries before the transaction commits, so the caller can write them back
+// to the file; if that fails nothing is imported and a later import does not
+// create the tasks twice.
+func applyImport(database *db.DB, entries []fileEntry, calendar int64, s settings, result *importResult, save func(ids []int64) error) error {
+    var updates, creates []db.Task
+    var createdFrom []int
+    unchanged, skipped := 0, 0
+    for i, e := range entries {
+        if e.taskID() == 0 {
+            t, err := e.task(db.Task{CalendarID: calendar}, s)
+            if err != nil {
+                return fmt.Errorf("%s: %v", e.where(), err)
+            }
+            creates = append(creates, t)
+            createdFrom = append(createdFrom, i)
+            continue
+        }
+
+        current, err := database.GetTask(e.taskID())
+        if errors.Is(err, db.ErrTaskNotFound) {
+            // Deleted here since it was exported; leave it deleted.
+            skipped++
+            continue
+        }
+        if err != nil {
+            return err
+        }
+        t, err := e.task(current, s)
+        if err != nil {
+            return fmt.Errorf("%s: %v", e.where(), err)
+        }
+        if t.Title == current.Title && t.StartsAt.Equal(current.StartsAt) && t.Duration == current.Duration && t.Done == current.Done {
+            unchanged++
+            continue
+        }
+        updates = append(updates, t)
+    }
+
+    var beforeCommit func([]int64) error
+    if len(creates) > 0 && save != nil {
+        beforeCommit = func(created []int64) error {
+            ids := make([]int64, len(entries))
+            for i, id := range created {
+                ids[createdFrom[i]] = id
+            }
+            return save(ids)
+        }
+    }
+    if _, err := database.ImportTasks(updates, creates, beforeCommit); err != nil {
+        return err
+    }
+    result.created += len(creates)
+    result.updated += len(updates)
+    result.unchanged += unchanged
+    result.skipped += skipped
+    return nil
+}
+
+// fileFormat is a file format that tasks are exported to and imported from.
+type fileFormat struct {
+    name string
+    // entries is what an import of the file is called in flag help.
+    entries string
+    export  func(tasks []db.Task, loc *time.Location) string
+    // apply imports lines. When it creates tasks it calls save with lines
+    // and the new tasks' IDs added, before the import is committed.
+    apply func(database *db.DB, lines []string, calendar int64, s settings, save func(out []string) error) (importResult, error)
+}
+
+func (f fileFormat) run(args []string) error {
+    usage := fmt.Sprintf("usage: scheduler %s export [-date DATE] [-days N] [-o FILE] | import [-calendar NAME] FILE", f.name)
+    if len(args) == 0 {
+        return errors.New(usage)
+    }
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+
+    switch args[0] {
+    case "export":
+        fs := flag.NewFlagSet(f.name+" export", flag.ContinueOnError)
+        var days dayRange
+        days.register(fs)
+        output := fs.String("o", "", "write to this file instead of stdout")
+        if err := fs.Parse(args[1:]); err != nil {
+            return err
+        }
+        first, err := days.first(s.location)
+        if err != nil {
+            return err
+        }
+
+        database, err := db.Open(s.dbPath)
+        if err != nil {
+            return fmt.Errorf("failed to initialize database: %v", err)
+        }
+        defer database.Close()
+        tasks, err := database.GetTasksBetween(first, first.AddDate(0, 0, days.days))
+        if err != nil {
+            return err
+        }
+
+        out := f.export(tasks, s.location)
+        if *output == "" {
+            fmt.Print(out)
+            return nil
+        }
+        return os.WriteFile(*output, []byte(out), 0644)
+
+    case "import":
+        fs := flag.NewFlagSet(f.name+" import", flag.ContinueOnError)
+        calendarName := fs.String("calendar", "Default", "calendar for "+f.entries+" that are not tasks yet")
+        if err := fs.Parse(args[1:]); err != nil {
+            return err
+        }
+        if fs.NArg() != 1 {
+            return errors.New(usage)
+        }
+        path := fs.Arg(0)
+        info, err := os.Stat(path)
+        if err != nil {
+            return err
+        }
+        data, err := os.ReadFile(path)
+        if err != nil {
+            return err
+        }
+
+        database, err := db.Open(s.dbPath)
+        if err != nil {
+            return fmt.Errorf("failed to initialize database: %v", err)
+        }
+        defer database.Close()
+        calendar, err := database.CalendarByName(*calendarName)
+        if err != nil {
+            return fmt.Errorf("-calendar: %v", err)
+        }
+
+        // The file with the new IDs is written before the import commits
+        // and moved into place after, so the file and the database agree
+        // even when something fails halfway.
+        tmp := path + ".tmp"
+        lines := strings.Split(string(data), "\n")
+        result, err := f.apply(database, lines, calendar.ID, s, func(out []string) error {
+            if err := os.WriteFile(tmp, []byte(strings.Join(out, "\n")), info.Mode().Perm()); err != nil {
+                return fmt.Errorf("failed to add task IDs: %v", err)
+            }
+            return nil
+        })
+        if err != nil {
+            os.Remove(tmp)
+            return fmt.Errorf("%s: %v", path, err)
+        }
+        if result.created > 0 {
+            if err := os.Rename(tmp, path); err != nil {
+                return fmt.Errorf("imported, but failed to replace %s with the copy that has the new task IDs, %s: %v", path, tmp, err)
+            }
+        }
+        fmt.Printf("created %d, updated %d, unchanged %d, skipped %d\n",
+            result.created, result.updated, result.unchanged, result.skipped)
+        return nil
+    }
+    return errors.New(usage)
+}
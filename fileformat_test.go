@@ -0,0 +1,111 @@
+package main
+
+import (
+    "errors"
+    "os"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestImportIsAllOrNothing(t *testing.T) {
+    t.Run("notes", func(t *testing.T) {
+        database := openTestDB(t)
+        s := testSettings(t, time.UTC)
+        s.notes = notesConfig{Dir: t.TempDir(), Filename: "{date}.md"}
+        first := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+        id, err := database.SaveTask(1, first.Add(9*time.Hour), "Standup", 15)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(s.notes.notePath(first), []byte("- [x] 09:00 Standup (15m)\n"), 0644); err != nil {
+            t.Fatal(err)
+        }
+
+        // The second day's note cannot be read, so the first day's checkbox
+        // may not be imported either.
+        broken := s.notes.notePath(first.AddDate(0, 0, 1))
+        if err := os.Mkdir(broken, 0755); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := importNotes(database, s, first, 2); err == nil {
+            t.Fatal("import with an unreadable note succeeded")
+        }
+        if task, _ := database.GetTask(id); task.Done {
+            t.Fatal("a failed import marked Standup done")
+        }
+
+        // Once the note can be read, trying again imports it.
+        if err := os.Remove(broken); err != nil {
+            t.Fatal(err)
+        }
+        result, err := importNotes(database, s, first, 2)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if result != (noteImport{done: 1}) {
+            t.Errorf("retry: %+v", result)
+        }
+        if task, _ := database.GetTask(id); !task.Done {
+            t.Errorf("Standup was not marked done")
+        }
+    })
+    t.Run("todo.txt", func(t *testing.T) {
+        database := openTestDB(t)
+        s := testSettings(t, time.UTC)
+        id, err := database.SaveTask(1, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), "Standup", 15)
+        if err != nil {
+            t.Fatal(err)
+        }
+        lines := []string{
+            "Standup, renamed due:2026-10-19 t:09:00 dur:15 scheduler:" + strconv.FormatInt(id, 10),
+            "Water plants due:2026-10-19 t:18:00 dur:20",
+            "x Pay rent due:2026-10-19 t:12:00",
+        }
+
+        // Writing the IDs back fails, so neither the update nor the new tasks
+        // may stay in the database.
+        _, err = importTodoTxt(database, lines, 1, s, func([]string) error {
+            return errors.New("disk full")
+        })
+        if err == nil || !strings.Contains(err.Error(), "disk full") {
+            t.Fatalf("got %v, want the save error", err)
+        }
+        day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+        tasks, err := database.GetTasksForDate(day)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if len(tasks) != 1 || tasks[0].Title != "Standup" {
+            t.Fatalf("after a failed import the day has %+v", tasks)
+        }
+
+        // Trying again imports everything once.
+        var out []string
+        result, err := importTodoTxt(database, lines, 1, s, func(lines []string) error {
+            out = lines
+            return nil
+        })
+        if err != nil {
+            t.Fatal(err)
+        }
+        if result != (importResult{created: 2, updated: 1}) {
+            t.Fatalf("retry: %+v", result)
+        }
+        if result, err := importTodoTxt(database, out, 1, s, nil); err != nil {
+            t.Fatal(err)
+        } else if result != (importResult{unchanged: 3}) {
+            t.Errorf("import of the written-back file: %+v", result)
+        }
+        tasks, _ = database.GetTasksForDate(day)
+        if len(tasks) != 3 {
+            t.Errorf("got %d tasks, want 3", len(tasks))
+        }
+        for _, task := range tasks {
+            if task.Title == "Pay rent" && !task.Done {
+                t.Errorf("Pay rent was imported as not done")
+            }
+        }
+    })
+}
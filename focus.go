@@ -0,0 +1,114 @@
+package main
+
+import (
+    "fmt"
+    "time"
+
+    "github.com/charmbracelet/bubbles/progress"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+type focusPhase int
+
+const (
+    focusWork focusPhase = iota
+    focusBreak
+)
+
+// focusTickMsg carries the session it was scheduled for so that ticks from a
+// stopped session die out instead of driving a new one.
+type focusTickMsg struct {
+    session int
+    time    time.Time
+}
+
+type focusSession struct {
+    id        int
+    task      Task
+    phase     focusPhase
+    startedAt time.Time
+    length    time.Duration
+    completed int
+    progress  progress.Model
+}
+
+func focusTick(session int) tea.Cmd {
+    return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+        return focusTickMsg{session: session, time: t}
+    })
+}
+
+func (m *model) startFocus(task Task) tea.Cmd {
+    m.focus = focusSession{
+        id:        m.focus.id + 1,
+        task:      task,
+        phase:     focusWork,
+        startedAt: time.Now(),
+        length:    m.settings.focusWork,
+        progress:  progress.New(progress.WithDefaultGradient(), progress.WithWidth(36)),
+    }
+    m.mode = focusMode
+    m.applyLayout()
+    return focusTick(m.focus.id)
+}
+
+func (m *model) stopFocus() {
+    m.focus.id++
+    m.mode = normalMode
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+}
+
+func (m *model) updateFocus(msg focusTickMsg) tea.Cmd {
+    if m.mode != focusMode || msg.session != m.focus.id {
+        return nil
+    }
+
+    if msg.time.Sub(m.focus.startedAt) < m.focus.length {
+        return focusTick(m.focus.id)
+    }
+
+    switch m.focus.phase {
+    case focusWork:
+        endedAt := m.focus.startedAt.Add(m.focus.length)
+        if err := m.db.LogPomodoro(m.focus.task.ID, m.focus.startedAt, endedAt); err != nil {
+            m.errorMsg = fmt.Sprintf("Failed to log pomodoro: %v", err)
+            m.errorTimer = time.Now()
+        }
+        m.focus.completed++
+        m.focus.phase = focusBreak
+        m.focus.length = m.settings.focusBreak
+    case focusBreak:
+        m.focus.phase = focusWork
+        m.focus.length = m.settings.focusWork
+    }
+    m.focus.startedAt = msg.time
+
+    return focusTick(m.focus.id)
+}
+
+func (m model) focusView() string {
+    elapsed := time.Since(m.focus.startedAt)
+    if elapsed > m.focus.length {
+        elapsed = m.focus.length
+    }
+    remaining := (m.focus.length - elapsed).Round(time.Second)
+
+    phase := "Focus"
+    if m.focus.phase == focusBreak {
+        phase = "Break"
+    }
+
+    return formStyle.Render(fmt.Sprintf(
+        "🍅 %s: %s\n\n%s %02d:%02d left\n%s\n\nCompleted this session: %d",
+        phase,
+        m.focus.task.Title,
+        phase,
+        int(remaining.Minutes()),
+        int(remaining.Seconds())%60,
+        m.focus.progress.ViewAs(float64(elapsed)/float64(m.focus.length)),
+        m.focus.completed,
+    ))
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/charmbracelet/bubbles/key"
+    "github.com/charmbracelet/bubbles/textinput"
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+)
+
+type gotoForm struct {
+    input  textinput.Model
+    picked time.Time
+    err    string
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d+)([dwmy]?)$`)
+
+// parseGotoDate reads anything quick-add understands as a date, plus offsets
+// from today such as +3d, -1w, +2m or +1y (a bare +3 counts days).
+func parseGotoDate(text string, today time.Time) (time.Time, error) {
+    words := strings.Fields(text)
+    if len(words) == 0 {
+        return time.Time{}, fmt.Errorf("enter a date")
+    }
+
+    if match := offsetPattern.FindStringSubmatch(strings.ToLower(text)); match != nil {
+        n, err := strconv.Atoi(match[2])
+        if err != nil || n > 10000 {
+            return time.Time{}, fmt.Errorf("offset %q is too large", text)
+        }
+        if match[1] == "-" {
+            n = -n
+        }
+        today = dayStart(today)
+        switch match[3] {
+        case "w":
+            return today.AddDate(0, 0, 7*n), nil
+        case "m":
+            return addMonths(today, n), nil
+        case "y":
+            return addMonths(today, 12*n), nil
+        }
+        return today.AddDate(0, 0, n), nil
+    }
+
+    if date, n := parseDateWords(words, today); n == len(words) {
+        return date, nil
+    }
+    return time.Time{}, fmt.Errorf("can't read %q as a date", text)
+}
+
+// addMonths moves date by n months, keeping to the last day of the month
+// when the day does not exist there (Jan 31 + 1 month is Feb 28).
+func addMonths(date time.Time, n int) time.Time {
+    first := time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, date.Location())
+    last := first.AddDate(0, 1, -1).Day()
+    day := date.Day()
+    if day > last {
+        day = last
+    }
+    return first.AddDate(0, 0, day-1)
+}
+
+func (m *model) openGoto() tea.Cmd {
+    ti := textinput.New()
+    ti.Placeholder = "2026-12-01, +2w, next fri"
+    ti.CharLimit = 32
+    ti.Focus()
+
+    m.gotoForm = gotoForm{input: ti, picked: dayStart(m.currentDate)}
+    m.mode = gotoMode
+    m.applyLayout()
+    return textinput.Blink
+}
+
+// movePicked moves the calendar selection and mirrors it in the prompt so
+// enter goes to what is highlighted.
+func (m *model) movePicked(date time.Time) {
+    m.gotoForm.picked = date
+    m.gotoForm.input.SetValue(date.Format("2006-01-02"))
+    m.gotoForm.input.CursorEnd()
+    m.gotoForm.err = ""
+}
+
+func (m *model) goTo(date time.Time) {
+    m.mode = normalMode
+    m.applyLayout()
+    m.currentDate = date
+    m.regenerateSlots()
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+    m.centerOnCursor()
+}
+
+func (m *model) updateGoto(msg tea.KeyMsg) tea.Cmd {
+    picked := m.gotoForm.picked
+    switch {
+    case key.Matches(msg, m.keys.gotoDate.Cancel):
+        m.mode = normalMode
+        m.applyLayout()
+        m.updateViewport()
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.Go):
+        if m.gotoForm.input.Value() != "" && m.gotoForm.err != "" {
+            return nil
+        }
+        m.goTo(picked)
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.PrevDay):
+        m.movePicked(picked.AddDate(0, 0, -1))
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.NextDay):
+        m.movePicked(picked.AddDate(0, 0, 1))
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.PrevWeek):
+        m.movePicked(picked.AddDate(0, 0, -7))
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.NextWeek):
+        m.movePicked(picked.AddDate(0, 0, 7))
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.PrevMonth):
+        m.movePicked(addMonths(picked, -1))
+        return nil
+    case key.Matches(msg, m.keys.gotoDate.NextMonth):
+        m.movePicked(addMonths(picked, 1))
+        return nil
+    }
+
+    var cmd tea.Cmd
+    m.gotoForm.input, cmd = m.gotoForm.input.Update(msg)
+    m.gotoForm.err = ""
+    if text := m.gotoForm.input.Value(); strings.TrimSpace(text) != "" {
+        date, err := parseGotoDate(text, m.now())
+        if err != nil {
+            m.gotoForm.err = err.Error()
+        } else {
+            m.gotoForm.picked = date
+        }
+    }
+    return cmd
+}
+
+// calendarView draws the month around picked, weeks starting on Monday.
+func calendarView(picked, today time.Time) string {
+    first := time.Date(picked.Year(), picked.Month(), 1, 0, 0, 0, 0, picked.Location())
+    title := first.Format("January 2006")
+
+    var b strings.Builder
+    b.WriteString(lipgloss.PlaceHorizontal(20, lipgloss.Center, title) + "\n")
+    b.WriteString("Mo Tu We Th Fr Sa Su")
+
+    day := weekStart(first)
+    for day.Month() == first.Month() || day.Before(first) {
+        b.WriteString("\n")
+        for i := 0; i < 7; i++ {
+            cell := "  "
+            if day.Month() == first.Month() {
+                cell = fmt.Sprintf("%2d", day.Day())
+                switch {
+                case sameDay(day, picked):
+                    cell = pickedDayStyle.Render(cell)
+                case sameDay(day, today):
+                    cell = todayStyle.Render(cell)
+                }
+            }
+            if i > 0 {
+                cell = " " + cell
+            }
+            b.WriteString(cell)
+            day = day.AddDate(0, 0, 1)
+        }
+    }
+    return b.String()
+}
+
+func sameDay(a, b time.Time) bool {
+    return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+func (m model) gotoView() string {
+    status := m.gotoForm.picked.Format("→ Monday, January 2, 2006")
+    if m.gotoForm.err != "" {
+        status = m.gotoForm.err
+    }
+    return formStyle.Render(fmt.Sprintf(
+        "Go to Date\n\n%s\n\n%s\n\n%s",
+        m.gotoForm.input.View(),
+        calendarView(m.gotoForm.picked, m.now()),
+        truncate(status, m.contentWidth()-4),
+    ))
+}
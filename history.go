@@ -0,0 +1,135 @@
+package main
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+// historyLines is how many changes the history pane shows before it starts
+// leaving out the oldest.
+const historyLines = 8
+
+// selectedTask returns the task under the cursor in taskSelectionMode.
+func (m model) selectedTask() (Task, bool) {
+    tasks := m.timeSlots[m.cursor].Tasks
+    if m.taskCursor < 0 || m.taskCursor >= len(tasks) {
+        return Task{}, false
+    }
+    return tasks[m.taskCursor], true
+}
+
+func (m *model) toggleHistory() {
+    m.showHistory = !m.showHistory
+    m.loadHistory()
+    m.updateViewport()
+}
+
+// loadHistory refreshes the pane for whichever task is selected now.
+func (m *model) loadHistory() {
+    m.history = nil
+    task, ok := m.selectedTask()
+    if !m.showHistory || !ok {
+        return
+    }
+    history, err := m.db.TaskHistory(task.ID)
+    if err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load history: %v", err)
+        m.errorTimer = time.Now()
+        return
+    }
+    m.history = history
+}
+
+func (m model) historyTime(value string) (time.Time, bool) {
+    t, err := db.ParseTimestamp(value)
+    if err != nil {
+        return t, false
+    }
+    return t.In(m.settings.location), true
+}
+
+// describeChange puts one history entry into words.
+func (m model) describeChange(e db.HistoryEntry) string {
+    const day = "Mon Jan 2 3:04 PM"
+    switch e.Field {
+    case "created":
+        if t, ok := m.historyTime(e.New); ok {
+            return "created for " + t.Format(day)
+        }
+        return "created"
+    case "starts_at":
+        from, okFrom := m.historyTime(e.Old)
+        to, okTo := m.historyTime(e.New)
+        if !okFrom || !okTo {
+            return "rescheduled"
+        }
+        verb := "pushed back"
+        if to.Before(from) {
+            verb = "moved earlier"
+        }
+        layout := day
+        if sameDay(from, to) {
+            layout = "3:04 PM"
+        }
+        return fmt.Sprintf("%s %s → %s", verb, from.Format(day), to.Format(layout))
+    case "title":
+        return fmt.Sprintf("renamed %q → %q", e.Old, e.New)
+    case "duration":
+        return fmt.Sprintf("duration %sm → %sm", e.Old, e.New)
+    case "done":
+        if e.New == "1" {
+            return "marked done"
+        }
+        return "marked not done"
+    case "calendar_id":
+        id, _ := strconv.ParseInt(e.New, 10, 64)
+        if c, ok := m.calendarByID(id); ok {
+            return "moved to " + c.Name
+        }
+        return "moved to another calendar"
+    }
+    return fmt.Sprintf("%s %s → %s", e.Field, e.Old, e.New)
+}
+
+func (m model) historyView() string {
+    task, _ := m.selectedTask()
+    width := m.contentWidth() - 4
+
+    var pushed, earlier int
+    for _, e := range m.history {
+        if e.Field != "starts_at" {
+            continue
+        }
+        from, okFrom := m.historyTime(e.Old)
+        to, okTo := m.historyTime(e.New)
+        switch {
+        case !okFrom || !okTo:
+        case to.After(from):
+            pushed++
+        case to.Before(from):
+            earlier++
+        }
+    }
+
+    var b strings.Builder
+    b.WriteString(truncate("History · "+task.Title, width) + "\n")
+    b.WriteString(fmt.Sprintf("Pushed back %d×, moved earlier %d×\n", pushed, earlier))
+
+    entries := m.history
+    if len(entries) > historyLines {
+        b.WriteString(fmt.Sprintf("\n… %d earlier changes", len(entries)-historyLines))
+        entries = entries[len(entries)-historyLines:]
+    }
+    for _, e := range entries {
+        when := e.ChangedAt.In(m.settings.location).Format("Jan 2 3:04 PM")
+        b.WriteString("\n" + truncate(fmt.Sprintf("%-15s %s", when, m.describeChange(e)), width))
+    }
+    if len(m.history) == 0 {
+        b.WriteString("\nNo changes recorded")
+    }
+    return formStyle.Render(b.String())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+func TestHistoryOutlivesDelete(t *testing.T) {
+    database := openTestDB(t)
+    startsAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+    id, err := database.SaveTask(1, startsAt, "Standup", 15)
+    if err != nil {
+        t.Fatal(err)
+    }
+    task, _ := database.GetTask(id)
+    task.Title = "Daily standup"
+    if err := database.UpdateTask(task); err != nil {
+        t.Fatal(err)
+    }
+    if err := database.DeleteTask(id); err != nil {
+        t.Fatal(err)
+    }
+
+    rows, err := database.Query(`SELECT field, COALESCE(old_value, '') FROM task_history WHERE task_id = ? ORDER BY id`, id)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer rows.Close()
+    var trail []string
+    for rows.Next() {
+        var field, old string
+        if err := rows.Scan(&field, &old); err != nil {
+            t.Fatal(err)
+        }
+        trail = append(trail, field+" "+old)
+    }
+    want := []string{"created ", "title Standup", "deleted Daily standup"}
+    if len(trail) != len(want) {
+        t.Fatalf("history after delete: %q, want %q", trail, want)
+    }
+    for i := range want {
+        if trail[i] != want[i] {
+            t.Errorf("history after delete: %q, want %q", trail, want)
+            break
+        }
+    }
+
+    // The highest ID is handed out again; the new task starts a fresh history.
+    reused, err := database.SaveTask(1, startsAt, "Retro", 30)
+    if err != nil {
+        t.Fatal(err)
+    }
+    history, err := database.TaskHistory(reused)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(history) != 1 || history[0].Field != "created" {
+        t.Errorf("history of task %d: %+v, want only its creation", reused, history)
+    }
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// icalItem is the part of a VEVENT or VTODO the scheduler keeps.
+type icalItem struct {
+    uid       string
+    component string
+    title     string
+    startsAt  time.Time
+    duration  int
+    done      bool
+    allDay    bool
+}
+
+const icalStamp = "20060102T150405Z"
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+var icalUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// encodeICal writes item as a one-component VCALENDAR. Start times are
+// written in UTC so no VTIMEZONE has to accompany them.
+func encodeICal(item icalItem) string {
+    var b strings.Builder
+    line := func(s string) {
+        // Lines longer than 75 octets are folded onto continuation lines.
+        for len(s) > 75 {
+            cut := 75
+            for cut > 1 && !utf8Start(s[cut]) {
+                cut--
+            }
+            b.WriteString(s[:cut] + "\r\n")
+            s = " " + s[cut:]
+        }
+        b.WriteString(s + "\r\n")
+    }
+
+    line("BEGIN:VCALENDAR")
+    line("VERSION:2.0")
+    line("PRODID:-//scheduler//EN")
+    line("BEGIN:" + item.component)
+    line("UID:" + item.uid)
+    line("DTSTAMP:" + time.Now().UTC().Format(icalStamp))
+    line("DTSTART:" + item.startsAt.UTC().Format(icalStamp))
+    line(fmt.Sprintf("DURATION:PT%dM", item.duration))
+    line("SUMMARY:" + icalEscaper.Replace(item.title))
+    if item.component == "VTODO" {
+        if item.done {
+            line("STATUS:COMPLETED")
+        } else {
+            line("STATUS:NEEDS-ACTION")
+        }
+    } else if item.done {
+        // Events have no completed state of their own.
+        line("X-SCHEDULER-DONE:TRUE")
+    }
+    line("END:" + item.component)
+    line("END:VCALENDAR")
+    return b.String()
+}
+
+func utf8Start(c byte) bool {
+    return c&0xC0 != 0x80
+}
+
+type icalProp struct {
+    name   string
+    params map[string]string
+    value  string
+}
+
+// unfoldICal splits a calendar object into properties, joining folded lines.
+func unfoldICal(data string) []icalProp {
+    var lines []string
+    sc := bufio.NewScanner(strings.NewReader(data))
+    sc.Buffer(make([]byte, 64*1024), 1<<20)
+    for sc.Scan() {
+        l := strings.TrimRight(sc.Text(), "\r")
+        if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+            lines[len(lines)-1] += l[1:]
+            continue
+        }
+        lines = append(lines, l)
+    }
+
+    props := make([]icalProp, 0, len(lines))
+    for _, l := range lines {
+        head, value, ok := strings.Cut(l, ":")
+        if !ok {
+            continue
+        }
+        parts := strings.Split(head, ";")
+        p := icalProp{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+        for _, param := range parts[1:] {
+            k, v, _ := strings.Cut(param, "=")
+            p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+        }
+        props = append(props, p)
+    }
+    return props
+}
+
+// parseICal reads the first VEVENT or VTODO of a calendar object. Times with
+// a TZID the system does not know, and floating times, are taken in loc.
+func parseICal(data string, loc *time.Location) (icalItem, error) {
+    var item icalItem
+    var end, due time.Time
+    depth := 0
+props:
+    for _, p := range unfoldICal(data) {
+        switch p.name {
+        case "BEGIN":
+            if item.component == "" && (p.value == "VEVENT" || p.value == "VTODO") {
+                item.component = p.value
+                depth = 1
+            } else if depth > 0 {
+                depth++
+            }
+            continue
+        case "END":
+            if depth > 0 {
+                depth--
+                if depth == 0 {
+                    break props
+                }
+            }
+            continue
+        }
+        // Skip nested components such as VALARM.
+        if depth != 1 {
+            continue
+        }
+
+        switch p.name {
+        case "UID":
+            item.uid = p.value
+        case "SUMMARY":
+            item.title = icalUnescaper.Replace(p.value)
+        case "DTSTART":
+            t, allDay, err := parseICalTime(p, loc)
+            if err != nil {
+                return item, err
+            }
+            item.startsAt, item.allDay = t, allDay
+        case "DTEND":
+            t, _, err := parseICalTime(p, loc)
+            if err != nil {
+                return item, err
+            }
+            end = t
+        case "DUE":
+            t, _, err := parseICalTime(p, loc)
+            if err != nil {
+                return item, err
+            }
+            due = t
+        case "DURATION":
+            d, err := parseICalDuration(p.value)
+            if err != nil {
+                return item, err
+            }
+            item.duration = int(d.Minutes())
+        case "STATUS":
+            item.done = item.done || p.value == "COMPLETED"
+        case "COMPLETED":
+            item.done = true
+        case "X-SCHEDULER-DONE":
+            item.done = strings.EqualFold(p.value, "TRUE")
+        }
+    }
+
+    if item.component == "" {
+        return item, fmt.Errorf("no VEVENT or VTODO found")
+    }
+    if item.uid == "" {
+        return item, fmt.Errorf("%s has no UID", item.component)
+    }
+    if item.startsAt.IsZero() {
+        item.startsAt = due
+    }
+    if item.startsAt.IsZero() {
+        return item, fmt.Errorf("%s %s has no start time", item.component, item.uid)
+    }
+    if item.duration == 0 {
+        switch {
+        case !end.IsZero():
+            item.duration = int(end.Sub(item.startsAt).Minutes())
+        case !due.IsZero() && due.After(item.startsAt):
+            item.duration = int(due.Sub(item.startsAt).Minutes())
+        }
+    }
+    return item, nil
+}
+
+func parseICalTime(p icalProp, loc *time.Location) (time.Time, bool, error) {
+    if p.params["VALUE"] == "DATE" || len(p.value) == 8 {
+        t, err := time.ParseInLocation("20060102", p.value, loc)
+        return t, true, err
+    }
+    if strings.HasSuffix(p.value, "Z") {
+        t, err := time.Parse(icalStamp, p.value)
+        return t, false, err
+    }
+    if tzid := p.params["TZID"]; tzid != "" {
+        if zone, err := time.LoadLocation(tzid); err == nil {
+            loc = zone
+        }
+    }
+    t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+    return t, false, err
+}
+
+var icalDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func parseICalDuration(s string) (time.Duration, error) {
+    m := icalDurationPattern.FindStringSubmatch(s)
+    if m == nil {
+        return 0, fmt.Errorf("invalid duration %q", s)
+    }
+    units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+    var d time.Duration
+    for i, unit := range units {
+        if m[i+2] != "" {
+            n, _ := strconv.Atoi(m[i+2])
+            d += time.Duration(n) * unit
+        }
+    }
+    if m[1] == "-" {
+        d = -d
+    }
+    return d, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestICalRoundTrip(t *testing.T) {
+    loc := newYork(t)
+    tests := []icalItem{
+        {
+            uid:       "short@test",
+            component: "VEVENT",
+            title:     "Standup",
+            startsAt:  time.Date(2026, 3, 8, 9, 0, 0, 0, loc),
+            duration:  15,
+        },
+        {
+            uid:       "long@test",
+            component: "VTODO",
+            title:     "Plan the offsite; book rooms, order food, and confirm the speaker — ¿qué más? ünïcödé",
+            startsAt:  time.Date(2026, 11, 1, 1, 30, 0, 0, loc),
+            duration:  90,
+            done:      true,
+        },
+        {
+            uid:       "event-done@test",
+            component: "VEVENT",
+            title:     "Line one\nline two \\ with a backslash",
+            startsAt:  time.Date(2026, 10, 19, 14, 0, 0, 0, loc),
+            duration:  45,
+            done:      true,
+        },
+    }
+
+    for _, want := range tests {
+        data := encodeICal(want)
+        for _, line := range strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n") {
+            if len(line) > 75 {
+                t.Errorf("%s: line of %d octets: %q", want.uid, len(line), line)
+            }
+        }
+
+        got, err := parseICal(data, time.UTC)
+        if err != nil {
+            t.Fatalf("%s: %v", want.uid, err)
+        }
+        if got.uid != want.uid || got.component != want.component || got.title != want.title ||
+            !got.startsAt.Equal(want.startsAt) || got.duration != want.duration || got.done != want.done || got.allDay {
+            t.Errorf("round trip of %s:\n got %+v\nwant %+v", want.uid, got, want)
+        }
+    }
+}
+
+func TestParseICalFoldingAndTZID(t *testing.T) {
+    data := strings.Join([]string{
+        "BEGIN:VCALENDAR",
+        "BEGIN:VEVENT",
+        "UID:folded@test",
+        "DTSTART;TZID=\"America/New_York\":20261101T090000",
+        "DTEND;TZID=America/New_York:20261101T101500",
+        "SUMMARY:A summary that was folded",
+        " \tacross lines",
+        "\t with a tab",
+        "BEGIN:VALARM",
+        "SUMMARY:Not the title",
+        "END:VALARM",
+        "END:VEVENT",
+        "END:VCALENDAR",
+    }, "\r\n")
+
+    item, err := parseICal(data, time.UTC)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if item.title != "A summary that was folded\tacross lines with a tab" {
+        t.Errorf("title %q", item.title)
+    }
+    // EST, since clocks went back earlier that morning.
+    if want := time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC); !item.startsAt.Equal(want) {
+        t.Errorf("starts at %v, want %v", item.startsAt, want)
+    }
+    if item.duration != 75 {
+        t.Errorf("duration %d, want 75", item.duration)
+    }
+
+    // An unknown TZID falls back to the given zone.
+    floating := strings.Replace(data, "America/New_York\"", "Nowhere/Special\"", 1)
+    loc := newYork(t)
+    item, err = parseICal(floating, loc)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if want := time.Date(2026, 11, 1, 9, 0, 0, 0, loc); !item.startsAt.Equal(want) {
+        t.Errorf("unknown TZID: starts at %v, want %v", item.startsAt, want)
+    }
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "unicode/utf8"
+
+    "github.com/charmbracelet/bubbles/key"
+)
+
+type normalKeys struct {
+    Up       key.Binding
+    Down     key.Binding
+    PrevDay  key.Binding
+    NextDay  key.Binding
+    NewTask  key.Binding
+    QuickAdd key.Binding
+    Goto     key.Binding
+    Calendars key.Binding
+    Open     key.Binding
+    Today    key.Binding
+    OffHours key.Binding
+    Report   key.Binding
+    Quit     key.Binding
+}
+
+type selectionKeys struct {
+    Up         key.Binding
+    Down       key.Binding
+    Focus      key.Binding
+    StartTimer key.Binding
+    StopTimer  key.Binding
+    Delete     key.Binding
+    History    key.Binding
+    Back       key.Binding
+}
+
+type creationKeys struct {
+    NextField key.Binding
+    Save      key.Binding
+    Cancel    key.Binding
+}
+
+type gotoKeys struct {
+    Go        key.Binding
+    PrevDay   key.Binding
+    NextDay   key.Binding
+    PrevWeek  key.Binding
+    NextWeek  key.Binding
+    PrevMonth key.Binding
+    NextMonth key.Binding
+    Cancel    key.Binding
+}
+
+type calendarKeys struct {
+    Up     key.Binding
+    Down   key.Binding
+    Toggle key.Binding
+    Use    key.Binding
+    Close  key.Binding
+}
+
+type focusKeys struct {
+    Stop key.Binding
+    Quit key.Binding
+}
+
+type reportKeys struct {
+    ToggleWeek     key.Binding
+    ExportMarkdown key.Binding
+    ExportCSV      key.Binding
+    Close          key.Binding
+    Quit           key.Binding
+}
+
+type keyMap struct {
+    normal    normalKeys
+    selection selectionKeys
+    creation  creationKeys
+    gotoDate  gotoKeys
+    calendars calendarKeys
+    focus     focusKeys
+    report    reportKeys
+}
+
+type keysConfig struct {
+    Preset    string              `toml:"preset"`
+    Normal    map[string][]string `toml:"normal"`
+    Selection map[string][]string `toml:"selection"`
+    Creation  map[string][]string `toml:"creation"`
+    Goto      map[string][]string `toml:"goto"`
+    Calendars map[string][]string `toml:"calendars"`
+    Focus     map[string][]string `toml:"focus"`
+    Report    map[string][]string `toml:"report"`
+}
+
+var keyNames = map[string]string{
+    "up":    "↑",
+    "down":  "↓",
+    "left":  "←",
+    "right": "→",
+    "shift+left":  "⇧←",
+    "shift+right": "⇧→",
+    " ":     "space",
+}
+
+func binding(desc string, keys ...string) key.Binding {
+    return key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKeys(keys), desc))
+}
+
+func helpKeys(keys []string) string {
+    names := make([]string, len(keys))
+    for i, k := range keys {
+        if name, ok := keyNames[k]; ok {
+            names[i] = name
+        } else {
+            names[i] = k
+        }
+    }
+    return strings.Join(names, "/")
+}
+
+func defaultKeyMap() keyMap {
+    return keyMap{
+        normal: normalKeys{
+            Up:       binding("up", "up"),
+            Down:     binding("down", "down"),
+            PrevDay:  binding("prev day", "left"),
+            NextDay:  binding("next day", "right"),
+            NewTask:  binding("new task", "n"),
+            QuickAdd: binding("quick add", "a"),
+            Goto:     binding("go to date", "g"),
+            Calendars: binding("calendars", "c"),
+            Open:     binding("open slot", "enter"),
+            Today:    binding("now", "t", "T"),
+            OffHours: binding("off hours", "o"),
+            Report:   binding("report", "r"),
+            Quit:     binding("quit", "q", "ctrl+c"),
+        },
+        selection: selectionKeys{
+            Up:         binding("up", "up"),
+            Down:       binding("down", "down"),
+            Focus:      binding("focus", "f"),
+            StartTimer: binding("start timer", "s"),
+            StopTimer:  binding("stop timer", "S"),
+            Delete:     binding("delete (twice)", "d"),
+            History:    binding("history", "i"),
+            Back:       binding("back", "esc"),
+        },
+        creation: creationKeys{
+            NextField: binding("switch field", "tab"),
+            Save:      binding("save", "enter"),
+            Cancel:    binding("cancel", "esc"),
+        },
+        gotoDate: gotoKeys{
+            Go:        binding("go", "enter"),
+            PrevDay:   binding("prev day", "shift+left"),
+            NextDay:   binding("next day", "shift+right"),
+            PrevWeek:  binding("prev week", "up"),
+            NextWeek:  binding("next week", "down"),
+            PrevMonth: binding("prev month", "pgup"),
+            NextMonth: binding("next month", "pgdown"),
+            Cancel:    binding("cancel", "esc"),
+        },
+        calendars: calendarKeys{
+            Up:     binding("up", "up"),
+            Down:   binding("down", "down"),
+            Toggle: binding("show/hide", " "),
+            Use:    binding("use for new tasks", "enter"),
+            Close:  binding("close", "esc", "c"),
+        },
+        focus: focusKeys{
+            Stop: binding("stop focus", "esc"),
+            Quit: binding("quit", "ctrl+c"),
+        },
+        report: reportKeys{
+            ToggleWeek:     binding("day/week", "w"),
+            ExportMarkdown: binding("export markdown", "m"),
+            ExportCSV:      binding("export csv", "c"),
+            Close:          binding("close", "esc", "r"),
+            Quit:           binding("quit", "q", "ctrl+c"),
+        },
+    }
+}
+
+func vimKeyMap() keyMap {
+    k := defaultKeyMap()
+    k.normal.Up = binding("up", "k", "up")
+    k.normal.Down = binding("down", "j", "down")
+    k.normal.PrevDay = binding("prev day", "h", "left")
+    k.normal.NextDay = binding("next day", "l", "right")
+    k.selection.Up = binding("up", "k", "up")
+    k.selection.Down = binding("down", "j", "down")
+    k.selection.Back = binding("back", "esc", "h")
+    k.calendars.Up = binding("up", "k", "up")
+    k.calendars.Down = binding("down", "j", "down")
+    return k
+}
+
+func emacsKeyMap() keyMap {
+    k := defaultKeyMap()
+    k.normal.Up = binding("up", "ctrl+p", "up")
+    k.normal.Down = binding("down", "ctrl+n", "down")
+    k.normal.PrevDay = binding("prev day", "ctrl+b", "left")
+    k.normal.NextDay = binding("next day", "ctrl+f", "right")
+    k.normal.Quit = binding("quit", "ctrl+x", "ctrl+c")
+    k.selection.Up = binding("up", "ctrl+p", "up")
+    k.selection.Down = binding("down", "ctrl+n", "down")
+    k.selection.Back = binding("back", "esc", "ctrl+g")
+    k.creation.Cancel = binding("cancel", "esc", "ctrl+g")
+    k.gotoDate.PrevWeek = binding("prev week", "ctrl+p", "up")
+    k.gotoDate.NextWeek = binding("next week", "ctrl+n", "down")
+    k.gotoDate.PrevMonth = binding("prev month", "alt+v", "pgup")
+    k.gotoDate.NextMonth = binding("next month", "ctrl+v", "pgdown")
+    k.gotoDate.Cancel = binding("cancel", "esc", "ctrl+g")
+    k.calendars.Up = binding("up", "ctrl+p", "up")
+    k.calendars.Down = binding("down", "ctrl+n", "down")
+    k.calendars.Close = binding("close", "esc", "ctrl+g", "c")
+    k.focus.Stop = binding("stop focus", "esc", "ctrl+g")
+    k.report.Close = binding("close", "esc", "ctrl+g", "r")
+    return k
+}
+
+// actions names each binding of a mode the way it is written in config.toml.
+func (k *keyMap) actions(mode string) map[string]*key.Binding {
+    switch mode {
+    case "normal":
+        return map[string]*key.Binding{
+            "up":        &k.normal.Up,
+            "down":      &k.normal.Down,
+            "prev_day":  &k.normal.PrevDay,
+            "next_day":  &k.normal.NextDay,
+            "new_task":  &k.normal.NewTask,
+            "quick_add": &k.normal.QuickAdd,
+            "goto":      &k.normal.Goto,
+            "calendars": &k.normal.Calendars,
+            "open":      &k.normal.Open,
+            "today":     &k.normal.Today,
+            "off_hours": &k.normal.OffHours,
+            "report":    &k.normal.Report,
+            "quit":      &k.normal.Quit,
+        }
+    case "selection":
+        return map[string]*key.Binding{
+            "up":          &k.selection.Up,
+            "down":        &k.selection.Down,
+            "focus":       &k.selection.Focus,
+            "start_timer": &k.selection.StartTimer,
+            "stop_timer":  &k.selection.StopTimer,
+            "delete":      &k.selection.Delete,
+            "history":     &k.selection.History,
+            "back":        &k.selection.Back,
+        }
+    case "creation":
+        return map[string]*key.Binding{
+            "next_field": &k.creation.NextField,
+            "save":       &k.creation.Save,
+            "cancel":     &k.creation.Cancel,
+        }
+    case "goto":
+        return map[string]*key.Binding{
+            "go":         &k.gotoDate.Go,
+            "prev_day":   &k.gotoDate.PrevDay,
+            "next_day":   &k.gotoDate.NextDay,
+            "prev_week":  &k.gotoDate.PrevWeek,
+            "next_week":  &k.gotoDate.NextWeek,
+            "prev_month": &k.gotoDate.PrevMonth,
+            "next_month": &k.gotoDate.NextMonth,
+            "cancel":     &k.gotoDate.Cancel,
+        }
+    case "calendars":
+        return map[string]*key.Binding{
+            "up":     &k.calendars.Up,
+            "down":   &k.calendars.Down,
+            "toggle": &k.calendars.Toggle,
+            "use":    &k.calendars.Use,
+            "close":  &k.calendars.Close,
+        }
+    case "focus":
+        return map[string]*key.Binding{
+            "stop": &k.focus.Stop,
+            "quit": &k.focus.Quit,
+        }
+    case "report":
+        return map[string]*key.Binding{
+            "toggle_week":     &k.report.ToggleWeek,
+            "export_markdown": &k.report.ExportMarkdown,
+            "export_csv":      &k.report.ExportCSV,
+            "close":           &k.report.Close,
+            "quit":            &k.report.Quit,
+        }
+    }
+    return nil
+}
+
+var keyModes = []string{"normal", "selection", "creation", "goto", "calendars", "focus", "report"}
+
+func (c keysConfig) overrides(mode string) map[string][]string {
+    switch mode {
+    case "normal":
+        return c.Normal
+    case "selection":
+        return c.Selection
+    case "creation":
+        return c.Creation
+    case "goto":
+        return c.Goto
+    case "calendars":
+        return c.Calendars
+    case "focus":
+        return c.Focus
+    case "report":
+        return c.Report
+    }
+    return nil
+}
+
+func (c keysConfig) keyMap() (keyMap, error) {
+    var k keyMap
+    switch c.Preset {
+    case "", "default":
+        k = defaultKeyMap()
+    case "vim":
+        k = vimKeyMap()
+    case "emacs":
+        k = emacsKeyMap()
+    default:
+        return k, fmt.Errorf("keys.preset: unknown preset %q (want default, vim or emacs)", c.Preset)
+    }
+
+    for _, mode := range keyModes {
+        actions := k.actions(mode)
+        for name, keys := range c.overrides(mode) {
+            b, ok := actions[name]
+            if !ok {
+                return k, fmt.Errorf("keys.%s: unknown action %q", mode, name)
+            }
+            if len(keys) == 0 {
+                return k, fmt.Errorf("keys.%s.%s: at least one key is required", mode, name)
+            }
+            *b = binding(b.Help().Desc, keys...)
+        }
+        if err := checkConflicts(mode, actions); err != nil {
+            return k, err
+        }
+    }
+
+    return k, nil
+}
+
+func checkConflicts(mode string, actions map[string]*key.Binding) error {
+    names := make([]string, 0, len(actions))
+    for name := range actions {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    owners := make(map[string]string)
+    for _, name := range names {
+        for _, k := range actions[name].Keys() {
+            if owner, ok := owners[k]; ok {
+                return fmt.Errorf("keys.%s: %q is bound to both %s and %s", mode, k, owner, name)
+            }
+            owners[k] = name
+            // The task form and the go-to prompt pass unbound keys to their
+            // text inputs, so a printable key there would make that character
+            // impossible to type.
+            if (mode == "creation" || mode == "goto") && utf8.RuneCountInString(k) == 1 {
+                return fmt.Errorf("keys.%s.%s: %q would stop it being typed into the prompt", mode, name, k)
+            }
+        }
+    }
+    return nil
+}
+
+func (k keyMap) help(m mode) []key.Binding {
+    switch m {
+    case normalMode:
+        n := k.normal
+        return []key.Binding{n.Up, n.Down, n.PrevDay, n.NextDay, n.NewTask, n.QuickAdd, n.Goto, n.Calendars, n.Open, n.Today, n.OffHours, n.Report, n.Quit}
+    case taskSelectionMode:
+        s := k.selection
+        return []key.Binding{s.Up, s.Down, s.Focus, s.StartTimer, s.StopTimer, s.Delete, s.History, s.Back}
+    case taskCreationMode:
+        c := k.creation
+        return []key.Binding{c.NextField, c.Save, c.Cancel}
+    case quickAddMode:
+        return []key.Binding{k.creation.Save, k.creation.Cancel}
+    case gotoMode:
+        g := k.gotoDate
+        return []key.Binding{g.Go, g.PrevDay, g.NextDay, g.PrevWeek, g.NextWeek, g.PrevMonth, g.NextMonth, g.Cancel}
+    case calendarMode:
+        c := k.calendars
+        return []key.Binding{c.Up, c.Down, c.Toggle, c.Use, c.Close}
+    case focusMode:
+        return []key.Binding{k.focus.Stop, k.focus.Quit}
+    case reportMode:
+        r := k.report
+        return []key.Binding{r.ToggleWeek, r.ExportMarkdown, r.ExportCSV, r.Close, r.Quit}
+    }
+    return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+    "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/x/ansi"
+)
+
+const (
+    minAppWidth     = 36
+    slotBoxHeight   = 3
+    defaultSlotRows = 6
+)
+
+// appWidth is the width of the app box including its border. Until the first
+// tea.WindowSizeMsg arrives it falls back to the original fixed layout.
+func (m model) appWidth() int {
+    if m.width == 0 {
+        return 52
+    }
+    if m.width < minAppWidth {
+        return minAppWidth
+    }
+    return m.width
+}
+
+// contentWidth is the room inside the app box's border and padding.
+func (m model) contentWidth() int {
+    return m.appWidth() - 6
+}
+
+func (m model) appStyle() lipgloss.Style {
+    return appStyle.Width(m.appWidth() - 2)
+}
+
+func (m model) slotStyle(style lipgloss.Style) lipgloss.Style {
+    return style.Width(m.contentWidth() - 2)
+}
+
+func (m *model) applyLayout() {
+    width := m.contentWidth() - 10
+    if width < 10 {
+        width = 10
+    }
+    m.taskForm.titleInput.Width = width
+    m.taskForm.durationInput.Width = width
+    m.quickAdd.input.Width = width
+    m.gotoForm.input.Width = width
+    m.focus.progress.Width = width
+}
+
+// slotBudget is the number of lines left for slot boxes and their tasks once
+// the header, form, error and help have been laid out.
+func (m model) slotBudget() int {
+    if m.height == 0 {
+        return defaultSlotRows * slotBoxHeight
+    }
+
+    header, form, help, errorDisplay := m.chrome()
+    used := lipgloss.Height(m.appStyle().Render(header + "\n" + errorDisplay + form + help))
+    budget := m.height - used
+    if budget < slotBoxHeight {
+        return slotBoxHeight
+    }
+    return budget
+}
+
+func (m model) slotHeight(i int) int {
+    return slotBoxHeight + len(m.timeSlots[i].Tasks)
+}
+
+// visibleRange picks the slots to render: it starts from the remembered
+// viewport top, moves just enough to keep the cursor on screen and then fills
+// the remaining height.
+func (m model) visibleRange() (int, int) {
+    n := len(m.timeSlots)
+    if n == 0 {
+        return 0, -1
+    }
+    budget := m.slotBudget()
+
+    top := m.viewport.top
+    if top < 0 {
+        top = 0
+    }
+    if top >= n {
+        top = n - 1
+    }
+    if m.cursor >= 0 && m.cursor < top {
+        top = m.cursor
+    }
+    for top < m.cursor {
+        used := 0
+        for i := top; i <= m.cursor; i++ {
+            used += m.slotHeight(i)
+        }
+        if used <= budget {
+            break
+        }
+        top++
+    }
+
+    return m.fillFrom(top, budget)
+}
+
+// fillFrom returns the range of slots that fit in budget lines starting at
+// top, pulling top back when the end of the day leaves room to spare.
+func (m model) fillFrom(top, budget int) (int, int) {
+    n := len(m.timeSlots)
+    bottom := top
+    used := m.slotHeight(top)
+    for bottom+1 < n && used+m.slotHeight(bottom+1) <= budget {
+        bottom++
+        used += m.slotHeight(bottom)
+    }
+    for bottom == n-1 && top > 0 && used+m.slotHeight(top-1) <= budget {
+        top--
+        used += m.slotHeight(top)
+    }
+    return top, bottom
+}
+
+// scrollViewport moves the viewport by delta slots, dragging the cursor
+// along only when it would otherwise leave the screen.
+func (m *model) scrollViewport(delta int) {
+    n := len(m.timeSlots)
+    if n == 0 {
+        return
+    }
+    top := m.viewport.top + delta
+    if top < 0 {
+        top = 0
+    }
+    if top >= n {
+        top = n - 1
+    }
+
+    top, bottom := m.fillFrom(top, m.slotBudget())
+    if m.cursor < top {
+        m.cursor = top
+    }
+    if m.cursor > bottom {
+        m.cursor = bottom
+    }
+    m.viewport.top = top
+    m.updateViewport()
+}
+
+// centerOnCursor sets the viewport top so the cursor sits roughly mid-screen.
+func (m *model) centerOnCursor() {
+    budget := m.slotBudget()
+    top := m.cursor
+    used := m.slotHeight(m.cursor)
+    for top > 0 && used+m.slotHeight(top-1) <= budget/2 {
+        top--
+        used += m.slotHeight(top)
+    }
+    m.viewport.top = top
+    m.updateViewport()
+}
+
+func truncate(s string, width int) string {
+    if width < 1 {
+        width = 1
+    }
+    return ansi.Truncate(s, width, "…")
+}
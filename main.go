@@ -6,10 +6,15 @@ import (
     "os"
     "time"
     "strconv"
+    "strings"
+    "unicode/utf8"
     
+    "github.com/charmbracelet/bubbles/help"
+    "github.com/charmbracelet/bubbles/key"
     "github.com/charmbracelet/bubbles/textinput"
     tea "github.com/charmbracelet/bubbletea"
     "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/x/ansi"
     
     "log"
 )
@@ -67,19 +72,80 @@ var (
         Padding(1, 2).
         MarginTop(1).
         MarginBottom(1)
+
+    selectedCurrentTimeSlotStyle = selectedTimeSlotStyle.
+        Background(lipgloss.Color("52"))
+
+    errorStyle = lipgloss.NewStyle().
+        Foreground(lipgloss.Color("196")).
+        Margin(1)
+
+    pickedDayStyle = lipgloss.NewStyle().
+        Bold(true).
+        Foreground(lipgloss.Color("255")).
+        Background(lipgloss.Color("52"))
+
+    todayStyle = lipgloss.NewStyle().
+        Bold(true).
+        Foreground(lipgloss.Color("39"))
 )
 
+func applyColors(c colorConfig) {
+    appStyle = appStyle.BorderForeground(lipgloss.Color(c.Border))
+    headerStyle = headerStyle.Foreground(lipgloss.Color(c.Header))
+    timeSlotStyle = timeSlotStyle.BorderForeground(lipgloss.Color(c.SlotBorder))
+    selectedTimeSlotStyle = selectedTimeSlotStyle.
+        BorderForeground(lipgloss.Color(c.SelectedBorder)).
+        Foreground(lipgloss.Color(c.SelectedText))
+    currentTimeSlotStyle = currentTimeSlotStyle.BorderForeground(lipgloss.Color(c.CurrentBorder))
+    taskStyle = taskStyle.Foreground(lipgloss.Color(c.Header))
+    normalTaskStyle = normalTaskStyle.Foreground(lipgloss.Color(c.Task))
+    selectedTaskStyle = selectedTaskStyle.Foreground(lipgloss.Color(c.SelectedTask))
+    formStyle = formStyle.BorderForeground(lipgloss.Color(c.FormBorder))
+    selectedCurrentTimeSlotStyle = selectedTimeSlotStyle.Background(lipgloss.Color(c.CurrentBackground))
+    errorStyle = errorStyle.Foreground(lipgloss.Color(c.Error))
+    pickedDayStyle = pickedDayStyle.
+        Foreground(lipgloss.Color(c.SelectedText)).
+        Background(lipgloss.Color(c.CurrentBackground))
+    todayStyle = todayStyle.Foreground(lipgloss.Color(c.CurrentBorder))
+}
+
 type tickMsg time.Time
 
+// watchMsg asks the model to look for changes other processes, such as the
+// rpc server or scheduler serve, committed to the database.
+type watchMsg struct{}
+
+const watchInterval = 2 * time.Second
+
+func watchTick() tea.Cmd {
+    return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+        return watchMsg{}
+    })
+}
+
 const ID = 1
 
 func doTick() tea.Msg {
     return tickMsg(time.Now())
 }
 
+// clockChange marks slots that sit on a daylight saving transition.
+type clockChange int
+
+const (
+    clockNormal    clockChange = iota
+    clockSkipped   // wall-clock time that does not exist because clocks went forward
+    clockFirstPass // wall-clock time that happens again once clocks go back
+    clockRepeated  // second occurrence of a wall-clock time after clocks went back
+)
+
 type TimeSlot struct {
     StartTime time.Time
+    EndTime   time.Time
     Tasks     []Task
+    Collapsed bool
+    Clock     clockChange
 }
 
 type Task struct {
@@ -88,6 +154,9 @@ type Task struct {
     Title    string
     Done     bool
     ID       int64
+    Pomodoros int
+    Tracked  time.Duration
+    Color    string
 }
 
 type mode int
@@ -96,6 +165,11 @@ const (
     normalMode mode = iota
     taskCreationMode
     taskSelectionMode
+    focusMode
+    reportMode
+    quickAddMode
+    gotoMode
+    calendarMode
 )
 
 type model struct {
@@ -109,9 +183,30 @@ type model struct {
     viewport    viewport
     mode        mode
     taskForm    taskForm
+    quickAdd    quickAddForm
+    gotoForm    gotoForm
+    calendars   []db.Calendar
+    calendar    int64
+    calendarCursor int
+    calendarNote string
+    dataVersion int64
     errorMsg    string
     errorTimer  time.Time
     deletePending bool
+    showHistory bool
+    history     []db.HistoryEntry
+    settings    settings
+    focus       focusSession
+    running     *db.TimeEntry
+    report      report
+    reportWeek  bool
+    reportNote  string
+    showOffHours bool
+    keys        keyMap
+    help        help.Model
+    width       int
+    height      int
+    lastClick   click
 }
 
 type taskForm struct {
@@ -124,12 +219,47 @@ type taskForm struct {
 type viewport struct {
     top    int
     bottom int
-    height int
 }
 
-func timeToSlotIndex(t time.Time) int {
-    minutes := t.Hour() * 60 + t.Minute()
-    return minutes / 30
+func slotIndexAt(slots []TimeSlot, t time.Time) int {
+    for i, slot := range slots {
+        if !t.Before(slot.StartTime) && t.Before(slot.EndTime) {
+            return i
+        }
+    }
+    return -1
+}
+
+func dayStart(date time.Time) time.Time {
+    return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// slotStartTime returns the wall-clock time minute minutes after midnight on
+// date. Times that clocks skip over resolve to a later instant.
+func slotStartTime(date time.Time, minute int) time.Time {
+    return time.Date(date.Year(), date.Month(), date.Day(), 0, minute, 0, 0, date.Location())
+}
+
+// clockShift returns how far clocks move if a zone transition happens exactly
+// at t: positive when they go forward, negative when they go back.
+func clockShift(t time.Time) time.Duration {
+    if zoneStart, _ := t.ZoneBounds(); !zoneStart.Equal(t) {
+        return 0
+    }
+    _, before := t.Add(-time.Second).Zone()
+    _, after := t.Zone()
+    return time.Duration(after-before) * time.Second
+}
+
+// nextSlotBoundary returns the next wall-clock multiple of slotMinutes after
+// t, stopping early at a zone transition.
+func nextSlotBoundary(t time.Time, slotMinutes int) time.Time {
+    minute := t.Hour()*60 + t.Minute()
+    next := t.Add(time.Duration(slotMinutes-minute%slotMinutes) * time.Minute)
+    if _, zoneEnd := t.ZoneBounds(); !zoneEnd.IsZero() && zoneEnd.Before(next) {
+        return zoneEnd
+    }
+    return next
 }
 
 func (m model) currentTaskCount () int {
@@ -139,33 +269,115 @@ func (m model) currentTaskCount () int {
     return 0
 }
 
-func generateTimeSlots(date time.Time) []TimeSlot {
-    slots := make([]TimeSlot, 48)
-    
-    baseTime := time.Date(
-        date.Year(), date.Month(), date.Day(),
-        0, 0, 0, 0, date.Location(),
-    )
-    
-    for i := range slots {
-        slots[i] = TimeSlot{
-            StartTime: baseTime.Add(time.Duration(i) * 30 * time.Minute),
+func generateTimeSlots(date time.Time, slotMinutes int, window dayWindow, showOffHours bool) []TimeSlot {
+    if showOffHours {
+        window = fullDay
+    }
+    window = window.snap(slotMinutes)
+
+    start, end := slotStartTime(date, window.start), slotStartTime(date, window.end)
+
+    slots := make([]TimeSlot, 0, 24*60/slotMinutes+2)
+    if window.start > 0 {
+        slots = append(slots, TimeSlot{
+            StartTime: slotStartTime(date, 0),
+            EndTime:   start,
+            Tasks:     make([]Task, 0),
+            Collapsed: true,
+        })
+    }
+
+    // Slots are walked in absolute time so that on daylight saving days the
+    // skipped hour and the repeated hour show up as they happen.
+    var repeatedUntil time.Time
+    for t := start; t.Before(end); {
+        if shift := clockShift(t); shift > 0 {
+            // The skipped slot starts and ends at the same instant so nothing
+            // can fall inside it; its start keeps the old offset for display.
+            name, offset := t.Add(-time.Second).Zone()
+            slots = append(slots, TimeSlot{
+                StartTime: t.In(time.FixedZone(name, offset)),
+                EndTime:   t,
+                Tasks:     make([]Task, 0),
+                Clock:     clockSkipped,
+            })
+        } else if shift < 0 {
+            repeatedUntil = t.Add(-shift)
+            for i := range slots {
+                if !slots[i].Collapsed && !slots[i].StartTime.Before(t.Add(shift)) {
+                    slots[i].Clock = clockFirstPass
+                }
+            }
+        }
+
+        next := nextSlotBoundary(t, slotMinutes)
+        if next.After(end) {
+            next = end
+        }
+        slot := TimeSlot{
+            StartTime: t,
+            EndTime:   next,
             Tasks:     make([]Task, 0),
         }
+        if t.Before(repeatedUntil) {
+            slot.Clock = clockRepeated
+        }
+        slots = append(slots, slot)
+        t = next
+    }
+
+    if window.end < 24*60 {
+        slots = append(slots, TimeSlot{
+            StartTime: end,
+            EndTime:   slotStartTime(date, 24*60),
+            Tasks:     make([]Task, 0),
+            Collapsed: true,
+        })
     }
     
     return slots
 }
 
-func initialTaskForm() taskForm {
+// now is the current time in the zone the schedule is displayed in.
+func (m model) now() time.Time {
+    return time.Now().In(m.settings.location)
+}
+
+func (m *model) regenerateSlots() {
+    minute := -1
+    var start time.Time
+    if m.cursor >= 0 && m.cursor < len(m.timeSlots) {
+        start = m.timeSlots[m.cursor].StartTime
+        minute = start.Hour()*60 + start.Minute()
+    }
+
+    window := m.settings.workHours[m.currentDate.Weekday()]
+    m.timeSlots = generateTimeSlots(m.currentDate, m.settings.slotMinutes, window, m.showOffHours)
+    m.currentTimeSlot = slotIndexAt(m.timeSlots, m.now())
+
+    if minute >= 0 {
+        // Stay on the same instant when it is still on screen so the cursor
+        // does not jump between the two passes of a repeated hour.
+        m.cursor = slotIndexAt(m.timeSlots, start)
+        if m.cursor < 0 {
+            m.cursor = slotIndexAt(m.timeSlots, slotStartTime(m.currentDate, minute))
+        }
+    }
+    if m.cursor < 0 || m.cursor >= len(m.timeSlots) {
+        m.cursor = 0
+    }
+    m.updateViewport()
+}
+
+func initialTaskForm(s settings) taskForm {
     ti := textinput.New()
     ti.Placeholder = "Task title"
     ti.Focus()
-    ti.CharLimit = 50
+    ti.CharLimit = s.titleCharLimit
     ti.Width = 40
 
     di := textinput.New()
-    di.Placeholder = "Duration (minutes)"
+    di.Placeholder = fmt.Sprintf("Duration (minutes, default %d)", s.defaultDuration)
     di.CharLimit = 3
     
     return taskForm{
@@ -175,57 +387,93 @@ func initialTaskForm() taskForm {
     }
 }
 
+// validateTask applies the rules the task form enforces through its inputs:
+// a title within title_char_limit and a duration of one to three digits.
+func validateTask(title string, duration int, s settings) error {
+    if strings.TrimSpace(title) == "" {
+        return fmt.Errorf("title cannot be empty")
+    }
+    if utf8.RuneCountInString(title) > s.titleCharLimit {
+        return fmt.Errorf("title is longer than %d characters", s.titleCharLimit)
+    }
+    if duration <= 0 || duration > 999 {
+        return fmt.Errorf("duration must be between 1 and 999 minutes")
+    }
+    return nil
+}
+
 func initialModel() model {
-    database, err := db.NewDB()
+    s, err := loadSettings()
+    if err != nil {
+        log.Fatalf("Failed to load config: %v\n", err)
+    }
+    colors := s.colors
+    if s.lightColors != s.colors && !lipgloss.HasDarkBackground() {
+        colors = s.lightColors
+    }
+    applyColors(colors)
+
+    database, err := db.Open(s.dbPath)
     if err != nil {
         log.Fatalf("Failed to initialize database: %v\n", err)
     }
 
-    currentDate := time.Now()
-    currentTime := timeToSlotIndex(currentDate)
     m := model{
         db:          database,
-        currentDate: currentDate,
-        currentTimeSlot: currentTime,
-        timeSlots:   generateTimeSlots(currentDate),
-        cursor:      currentTime,
-        selected:    currentTime,
-        viewport: viewport{
-            top:    currentTime - 3,
-            bottom: currentTime + 2,
-            height: 6,
-        },
         taskCursor: 0,
         deletePending: false,
         mode:     normalMode,
-        taskForm: initialTaskForm(),
-    }
-
-    if err := m.loadTasks(); err != nil {
-        log.Printf("Failed to load initial tasks: %v\n", err)
+        taskForm: initialTaskForm(s),
+        settings: s,
+        keys:     s.keys,
+        help:     help.New(),
+        reportWeek: true,
     }
+    m.dataVersion, _ = database.DataVersion()
+    m.jumpToCurrentTime()
     return m
 }
 
 func (m *model) jumpToCurrentTime() {
-    now := time.Now()
+    now := m.now()
     m.currentDate = now
-    m.currentTimeSlot = timeToSlotIndex(now)
+    m.cursor = -1
+    m.regenerateSlots()
     m.cursor = m.currentTimeSlot
-    m.timeSlots = generateTimeSlots(m.currentDate)
+    m.selected = m.cursor
     
-    m.viewport.top = m.currentTimeSlot - 3
-    m.viewport.bottom = m.currentTimeSlot + 2
-    
-    if m.viewport.top < 0 {
-        m.viewport.top = 0
-        m.viewport.bottom = 5
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+    m.centerOnCursor()
+}
+
+func (m *model) openTaskForm() tea.Cmd {
+    if m.timeSlots[m.cursor].Collapsed {
+        m.toggleOffHours()
+        return nil
     }
-    if m.viewport.bottom >= len(m.timeSlots) {
-        m.viewport.bottom = len(m.timeSlots) - 1
-        m.viewport.top = m.viewport.bottom - 5
+    if m.timeSlots[m.cursor].Clock == clockSkipped {
+        m.errorMsg = "This hour is skipped when clocks go forward"
+        m.errorTimer = time.Now()
+        return nil
+    }
+    m.selected = m.cursor
+    m.mode = taskCreationMode
+    m.taskForm = initialTaskForm(m.settings)
+    m.applyLayout()
+    return textinput.Blink
+}
+
+func (m *model) toggleOffHours() {
+    onCurrentSlot := m.cursor == m.currentTimeSlot
+    m.showOffHours = !m.showOffHours
+    m.regenerateSlots()
+    if onCurrentSlot && m.currentTimeSlot >= 0 {
+        m.cursor = m.currentTimeSlot
+        m.updateViewport()
     }
-    
     if err := m.loadTasks(); err != nil {
         m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
         m.errorTimer = time.Now()
@@ -234,6 +482,9 @@ func (m *model) jumpToCurrentTime() {
 
 
 func (m *model) loadTasks() error {
+    if err := m.loadCalendars(); err != nil {
+        return err
+    }
     tasks, err := m.db.GetTasksForDate(m.currentDate)
     if err != nil {
         return err
@@ -244,56 +495,98 @@ func (m *model) loadTasks() error {
     }
     
     for _, task := range tasks {
-        if task.TimeSlot >= 0 && task.TimeSlot < len(m.timeSlots) {
-            m.timeSlots[task.TimeSlot].Tasks = append(
-                m.timeSlots[task.TimeSlot].Tasks,
+        calendar, _ := m.calendarByID(task.CalendarID)
+        if calendar.Hidden {
+            continue
+        }
+        slot := slotIndexAt(m.timeSlots, task.StartsAt)
+        if slot >= 0 {
+            m.timeSlots[slot].Tasks = append(
+                m.timeSlots[slot].Tasks,
                 Task{
-                    Time:     m.timeSlots[task.TimeSlot].StartTime,
+                    Time:     task.StartsAt.In(m.settings.location),
                     Duration: task.Duration,
                     Title:    task.Title,
                     Done:     task.Done,
                     ID:       task.ID,
+                    Pomodoros: task.Pomodoros,
+                    Tracked:  task.Tracked,
+                    Color:    calendar.Color,
                 },
             )
         }
     }
     
-    return nil
+    return m.loadRunningTimer()
 }
 
 
 
 func (m model) Init() tea.Cmd {
-    return textinput.Blink
+    if m.running != nil {
+        return tea.Batch(textinput.Blink, watchTick(), startupBackup(m.settings), trackTick(m.running.ID))
+    }
+    return tea.Batch(textinput.Blink, watchTick(), startupBackup(m.settings))
 }
 
-func (m *model) updateViewport() {
-    if m.cursor > m.viewport.bottom {
-        diff := m.cursor - m.viewport.bottom
-        m.viewport.top += diff
-        m.viewport.bottom += diff
+// reloadIfChanged picks up tasks written by other processes while the TUI is
+// open, keeping the task cursor in range.
+func (m *model) reloadIfChanged() tea.Cmd {
+    v, err := m.db.DataVersion()
+    if err != nil || v == m.dataVersion {
+        return watchTick()
     }
-    
-    if m.cursor < m.viewport.top {
-        diff := m.viewport.top - m.cursor
-        m.viewport.top -= diff
-        m.viewport.bottom -= diff
+    m.dataVersion = v
+
+    var runningID int64
+    if m.running != nil {
+        runningID = m.running.ID
     }
-    
-    if m.viewport.bottom >= len(m.timeSlots) {
-        m.viewport.bottom = len(m.timeSlots) - 1
-        m.viewport.top = m.viewport.bottom - m.viewport.height + 1
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+        return watchTick()
     }
-    
-    if m.viewport.top < 0 {
-        m.viewport.top = 0
-        m.viewport.bottom = m.viewport.top + m.viewport.height - 1
+    // A timer started elsewhere needs its own tick to keep the header live.
+    var track tea.Cmd
+    if m.running != nil && m.running.ID != runningID {
+        track = trackTick(m.running.ID)
+    }
+    if m.mode == taskSelectionMode {
+        n := len(m.timeSlots[m.cursor].Tasks)
+        if n == 0 {
+            m.mode = normalMode
+            m.deletePending = false
+            m.showHistory = false
+        } else if m.taskCursor >= n {
+            m.taskCursor = n - 1
+        }
+        m.loadHistory()
     }
+    m.updateViewport()
+    return tea.Batch(watchTick(), track)
+}
+
+func (m *model) updateViewport() {
+    m.viewport.top, m.viewport.bottom = m.visibleRange()
 }
 
 func formatTimeSlot(slot TimeSlot) string {
-    startTime := slot.StartTime.Format("3:04 PM") 
-    endTime := slot.StartTime.Add(30 * time.Minute).Format("3:04 PM")
+    startTime := slot.StartTime.Format("3:04 PM")
+    if slot.Clock == clockSkipped {
+        return fmt.Sprintf("%s - %s skipped (clocks go forward)", startTime, slot.EndTime.Format("3:04 PM"))
+    }
+
+    // The end is shown with the start's offset so a slot ending on a
+    // transition does not appear to end before it starts.
+    name, offset := slot.StartTime.Zone()
+    endTime := slot.EndTime.In(time.FixedZone(name, offset)).Format("3:04 PM")
+    switch slot.Clock {
+    case clockFirstPass:
+        return fmt.Sprintf("%s - %s %s", startTime, endTime, name)
+    case clockRepeated:
+        return fmt.Sprintf("%s - %s %s (repeated)", startTime, endTime, name)
+    }
     return fmt.Sprintf("%s - %s", startTime, endTime)
 }
 
@@ -303,86 +596,112 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     var cmds []tea.Cmd
 
     switch msg := msg.(type) {
+    case tea.WindowSizeMsg:
+        m.width = msg.Width
+        m.height = msg.Height
+        m.applyLayout()
+        m.updateViewport()
+
+    case tea.MouseMsg:
+        return m, m.handleMouse(msg)
+
+    case backupMsg:
+        if msg.err != nil {
+            m.errorMsg = fmt.Sprintf("Failed to back up: %v", msg.err)
+            m.errorTimer = time.Now()
+        }
+        return m, nil
+
     case tea.KeyMsg:
         switch m.mode {
         case normalMode:
-            switch msg.String() {
-            case "ctrl+c", "q":
+            switch {
+            case key.Matches(msg, m.keys.normal.Quit):
                 return m, tea.Quit
-            case "left":
+            case key.Matches(msg, m.keys.normal.PrevDay):
                 m.currentDate = m.currentDate.AddDate(0, 0, -1)
-                m.timeSlots = generateTimeSlots(m.currentDate)
+                m.regenerateSlots()
                 if err := m.loadTasks(); err != nil {
                     m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
                     m.errorTimer = time.Now()
                 }
-            case "right":
+            case key.Matches(msg, m.keys.normal.NextDay):
                 m.currentDate = m.currentDate.AddDate(0, 0, 1)
-                m.timeSlots = generateTimeSlots(m.currentDate)
+                m.regenerateSlots()
                 if err := m.loadTasks(); err != nil {
                     m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
                     m.errorTimer = time.Now()
                 }
-            case "up":
+            case key.Matches(msg, m.keys.normal.Up):
                 if m.cursor > 0 {
                     m.cursor--
                     m.updateViewport()
                 }
-            case "down":
+            case key.Matches(msg, m.keys.normal.Down):
                 if m.cursor < len(m.timeSlots)-1 {
                     m.cursor++
                     m.updateViewport()
                 }
-            case "n":
-                m.selected = m.cursor
-                m.mode = taskCreationMode
-                m.taskForm = initialTaskForm()
-                return m, textinput.Blink
-            case "enter":
-                if len(m.timeSlots[m.cursor].Tasks) > 0 {
+            case key.Matches(msg, m.keys.normal.NewTask):
+                return m, m.openTaskForm()
+            case key.Matches(msg, m.keys.normal.QuickAdd):
+                return m, m.openQuickAdd()
+            case key.Matches(msg, m.keys.normal.Goto):
+                return m, m.openGoto()
+            case key.Matches(msg, m.keys.normal.Calendars):
+                m.openCalendars()
+            case key.Matches(msg, m.keys.normal.Open):
+                if m.timeSlots[m.cursor].Collapsed {
+                    m.toggleOffHours()
+                } else if len(m.timeSlots[m.cursor].Tasks) > 0 {
                     m.mode = taskSelectionMode
                     m.taskCursor = 0
                 }
-            case "t", "T":
-                now := time.Now()
-                m.currentDate = now
-                m.currentTimeSlot = timeToSlotIndex(now)
-                m.cursor = m.currentTimeSlot
-                m.timeSlots = generateTimeSlots(m.currentDate)
-                
-                m.viewport.top = m.currentTimeSlot - 3
-                m.viewport.bottom = m.currentTimeSlot + 2
-                
-                if m.viewport.top < 0 {
-                    m.viewport.top = 0
-                    m.viewport.bottom = 5
-                }
-                if m.viewport.bottom >= len(m.timeSlots) {
-                    m.viewport.bottom = len(m.timeSlots) - 1
-                    m.viewport.top = m.viewport.bottom - 5
-                }
-                
-                if err := m.loadTasks(); err != nil {
-                    m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
-                    m.errorTimer = time.Now()
-                }
+            case key.Matches(msg, m.keys.normal.Report):
+                m.openReport()
+            case key.Matches(msg, m.keys.normal.OffHours):
+                m.toggleOffHours()
+            case key.Matches(msg, m.keys.normal.Today):
+                m.jumpToCurrentTime()
             }
         
         case taskSelectionMode:
-            switch msg.String() {
-            case "esc":
+            switch {
+            case key.Matches(msg, m.keys.selection.Back):
                 m.mode = normalMode
                 m.taskCursor = 0
                 m.deletePending = false
-            case "up":
+                m.showHistory = false
+                m.updateViewport()
+            case key.Matches(msg, m.keys.selection.Up):
                 if m.taskCursor > 0 {
                     m.taskCursor--
+                    m.loadHistory()
                 }
-            case "down":
+            case key.Matches(msg, m.keys.selection.Down):
                 if m.taskCursor < len(m.timeSlots[m.cursor].Tasks)-1 {
                     m.taskCursor++
+                    m.loadHistory()
                 }
-            case "d":
+            case key.Matches(msg, m.keys.selection.History):
+                m.deletePending = false
+                m.toggleHistory()
+            case key.Matches(msg, m.keys.selection.Focus):
+                m.deletePending = false
+                tasks := m.timeSlots[m.cursor].Tasks
+                if m.taskCursor < len(tasks) {
+                    return m, m.startFocus(tasks[m.taskCursor])
+                }
+            case key.Matches(msg, m.keys.selection.StartTimer):
+                m.deletePending = false
+                tasks := m.timeSlots[m.cursor].Tasks
+                if m.taskCursor < len(tasks) {
+                    return m, m.startTracking(tasks[m.taskCursor])
+                }
+            case key.Matches(msg, m.keys.selection.StopTimer):
+                m.deletePending = false
+                m.stopTracking()
+            case key.Matches(msg, m.keys.selection.Delete):
                 if !m.deletePending{
                     m.deletePending = true
                     return m, nil
@@ -402,9 +721,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                             
                             if len(m.timeSlots[m.cursor].Tasks) == 0 {
                                 m.mode = normalMode
+                                m.showHistory = false
                             } else if m.taskCursor >= len(m.timeSlots[m.cursor].Tasks) {
                                 m.taskCursor = len(m.timeSlots[m.cursor].Tasks) - 1
                             }
+                            m.loadHistory()
+                            m.updateViewport()
                         }
                     }
                     m.deletePending = false
@@ -413,12 +735,74 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 m.deletePending = false
             }
         
+        case reportMode:
+            switch {
+            case key.Matches(msg, m.keys.report.Quit):
+                return m, tea.Quit
+            case key.Matches(msg, m.keys.report.Close):
+                m.mode = normalMode
+            case key.Matches(msg, m.keys.report.ToggleWeek):
+                m.reportWeek = !m.reportWeek
+                m.openReport()
+            case key.Matches(msg, m.keys.report.ExportMarkdown):
+                m.exportReport("md")
+            case key.Matches(msg, m.keys.report.ExportCSV):
+                m.exportReport("csv")
+            }
+
+        case focusMode:
+            switch {
+            case key.Matches(msg, m.keys.focus.Quit):
+                return m, tea.Quit
+            case key.Matches(msg, m.keys.focus.Stop):
+                m.stopFocus()
+            }
+
+        case gotoMode:
+            return m, m.updateGoto(msg)
+
+        case calendarMode:
+            switch {
+            case key.Matches(msg, m.keys.calendars.Close):
+                m.mode = normalMode
+                m.applyLayout()
+                m.updateViewport()
+            case key.Matches(msg, m.keys.calendars.Up):
+                if m.calendarCursor > 0 {
+                    m.calendarCursor--
+                }
+            case key.Matches(msg, m.keys.calendars.Down):
+                if m.calendarCursor < len(m.calendars)-1 {
+                    m.calendarCursor++
+                }
+            case key.Matches(msg, m.keys.calendars.Toggle):
+                m.toggleCalendar()
+            case key.Matches(msg, m.keys.calendars.Use):
+                m.useCalendar()
+            }
+
+        case quickAddMode:
+            switch {
+            case key.Matches(msg, m.keys.creation.Cancel):
+                m.mode = normalMode
+                m.applyLayout()
+                m.updateViewport()
+                return m, nil
+            case key.Matches(msg, m.keys.creation.Save):
+                m.saveQuickAdd()
+                return m, nil
+            }
+
+            m.quickAdd.err = ""
+            m.quickAdd.input, cmd = m.quickAdd.input.Update(msg)
+            cmds = append(cmds, cmd)
+
         case taskCreationMode:
-            switch msg.String() {
-            case "esc":
+            switch {
+            case key.Matches(msg, m.keys.creation.Cancel):
                 m.mode = normalMode
                 m.taskForm.err = ""
-            case "tab":
+            case key.Matches(msg, m.keys.creation.NextField):
                 m.taskForm.activeInput = (m.taskForm.activeInput + 1) % 2
                 if m.taskForm.activeInput == 0 {
                     m.taskForm.titleInput.Focus()
@@ -427,13 +811,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                     m.taskForm.titleInput.Blur()
                     m.taskForm.durationInput.Focus()
                 }
-            case "enter":
+            case key.Matches(msg, m.keys.creation.Save):
                 if m.taskForm.titleInput.Value() == "" {
                     m.taskForm.err = "Title cannot be empty"
                     return m, nil
                 }
 
-                duration := 30 
+                duration := m.settings.defaultDuration
                 if m.taskForm.durationInput.Value() != "" {
                     var err error
                     duration, err = strconv.Atoi(m.taskForm.durationInput.Value())
@@ -443,9 +827,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                     }
                 }
                 
-                err := m.db.SaveTask(
-                    m.currentDate,
-                    m.cursor,
+                _, err := m.db.SaveTask(
+                    m.calendar,
+                    m.timeSlots[m.cursor].StartTime,
                     m.taskForm.titleInput.Value(),
                     duration,
                 )
@@ -461,7 +845,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
                 }
                 
                 m.mode = normalMode
-                m.taskForm = initialTaskForm()
+                m.taskForm = initialTaskForm(m.settings)
+                m.applyLayout()
+                m.updateViewport()
                 return m, nil
             }
             
@@ -474,8 +860,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
             }
         }
     
+    case focusTickMsg:
+        return m, m.updateFocus(msg)
+
+    case trackTickMsg:
+        return m, m.updateTracking(msg)
+
+    case watchMsg:
+        return m, m.reloadIfChanged()
+
     case tickMsg:
-        newTimeSlot := timeToSlotIndex(time.Time(msg))
+        newTimeSlot := slotIndexAt(m.timeSlots, time.Time(msg))
         if newTimeSlot != m.currentTimeSlot {
             m.currentTimeSlot = newTimeSlot
             return m, nil
@@ -485,30 +880,117 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
     return m, tea.Batch(cmds...)
 }
 
-func (m model) View() string {
+func (m model) chrome() (header, form, help, errorDisplay string) {
     // Header with current date
-    header := headerStyle.Render(fmt.Sprintf("📅 %s", m.currentDate.Format("Monday, January 2, 2006")))
+    headerText := fmt.Sprintf("📅 %s", m.currentDate.Format("Monday, January 2, 2006"))
+    if c, ok := m.calendarByID(m.calendar); ok && len(m.calendars) > 1 {
+        headerText += " · " + c.Name
+    }
+    if timer := m.runningTimerView(); timer != "" {
+        headerText += "\n" + truncate(timer, m.contentWidth())
+    }
+    header = headerStyle.Render(headerText)
+    
+    // Task creation form
+    if m.mode == taskCreationMode {
+        form = formStyle.Render(fmt.Sprintf(
+            "New Task at %s\n\n%s\n%s\n\n%s",
+            formatTimeSlot(m.timeSlots[m.cursor]),
+            m.taskForm.titleInput.View(),
+            m.taskForm.durationInput.View(),
+            m.taskForm.err,
+        ))
+    }
+    if m.mode == quickAddMode {
+        form = m.quickAddView()
+    }
+    if m.mode == gotoMode {
+        form = m.gotoView()
+    }
+    if m.mode == calendarMode {
+        form = m.calendarsView()
+    }
+    if m.mode == focusMode {
+        form = m.focusView()
+    }
+    if m.mode == taskSelectionMode && m.showHistory {
+        form = m.historyView()
+    }
+    
+    // Help text
+    help = "\n" + m.help.ShortHelpView(m.keys.help(m.mode))
+    
+    // Error message
+    if m.errorMsg != "" && time.Since(m.errorTimer) < 3*time.Second {
+        errorDisplay = errorStyle.Render(m.errorMsg)
+    }
+
+    return header, form, help, errorDisplay
+}
+
+// taskLine returns the bullet and the text of a task's line separately so the
+// bullet can take its calendar's color.
+func (m model) taskLine(slot TimeSlot, task Task) (string, string) {
+    title := task.Title
+    if slot.Collapsed || !task.Time.Equal(slot.StartTime) {
+        title = task.Time.Format("3:04") + " " + title
+    }
+
+    prefix := " • "
+    suffix := fmt.Sprintf(" (%dm)", task.Duration)
+    if task.Done {
+        prefix = " ✓ "
+        suffix = ""
+    }
+    if task.Pomodoros > 0 {
+        suffix += fmt.Sprintf(" 🍅%d", task.Pomodoros)
+    }
+    if m.running != nil && m.running.TaskID == task.ID {
+        suffix += " ⏱"
+    } else if task.Tracked > 0 {
+        suffix += fmt.Sprintf(" ⏱%dm", int(task.Tracked.Minutes()))
+    }
+
+    // One column goes to the task styles' left padding.
+    room := m.contentWidth() - 1 - ansi.StringWidth(prefix+suffix)
+    return prefix, truncate(title, room) + suffix
+}
+
+func (m model) View() string {
+    header, form, help, errorDisplay := m.chrome()
+
+    if m.mode == reportMode {
+        body := m.report.view()
+        if m.reportNote != "" {
+            body += "\n\n" + m.reportNote
+        }
+        return m.appStyle().Render(header + "\n" + body + "\n" + help)
+    }
     
     // Time slots view
     var slots string
-    for i := m.viewport.top; i <= m.viewport.bottom; i++ {
+    top, bottom := m.visibleRange()
+    for i := top; i <= bottom; i++ {
         slot := m.timeSlots[i]
         timeStr := formatTimeSlot(slot)
+        if slot.Collapsed {
+            timeStr = fmt.Sprintf("▸ %s (off hours)", timeStr)
+        }
         
         // Determine time slot style
         var style lipgloss.Style
         switch {
-        case i == m.cursor && i == m.currentTimeSlot && m.currentDate.Format("2006-01-02") == time.Now().Format("2006-01-02"):
-            style = selectedTimeSlotStyle.Copy().Background(lipgloss.Color("52"))
+        case i == m.cursor && i == m.currentTimeSlot && m.currentDate.Format("2006-01-02") == m.now().Format("2006-01-02"):
+            style = selectedCurrentTimeSlotStyle
         case i == m.cursor:
             style = selectedTimeSlotStyle
-        case i == m.currentTimeSlot && m.currentDate.Format("2006-01-02") == time.Now().Format("2006-01-02"):
+        case i == m.currentTimeSlot && m.currentDate.Format("2006-01-02") == m.now().Format("2006-01-02"):
             style = currentTimeSlotStyle
         default:
             style = timeSlotStyle
         }
         
-        slots += style.Render(timeStr) + "\n"
+        slots += m.slotStyle(style).Render(timeStr) + "\n"
         
         // Show tasks for this time slot
         if len(slot.Tasks) > 0 {
@@ -522,51 +1004,28 @@ func (m model) View() string {
                     taskStyle = normalTaskStyle
                 }
                 
-                taskStr := fmt.Sprintf(" • %s (%dm)", task.Title, task.Duration)
-                if task.Done {
-                    taskStr = fmt.Sprintf(" ✓ %s", task.Title)
+                prefix, text := m.taskLine(slot, task)
+                bulletStyle := taskStyle
+                if task.Color != "" {
+                    bulletStyle = bulletStyle.Foreground(lipgloss.Color(task.Color))
                 }
-                slots += taskStyle.Render(taskStr) + "\n"
+                slots += bulletStyle.Render(prefix) + taskStyle.UnsetPaddingLeft().Render(text) + "\n"
             }
         }
     }
     
-    // Task creation form
-    var form string
-    if m.mode == taskCreationMode {
-        form = formStyle.Render(fmt.Sprintf(
-            "New Task at %s\n\n%s\n%s\n\n%s\n\nTab: Switch fields • Enter: Save • Esc: Cancel",
-            formatTimeSlot(m.timeSlots[m.cursor]),
-            m.taskForm.titleInput.View(),
-            m.taskForm.durationInput.View(),
-            m.taskForm.err,
-        ))
-    }
-    
-    // Help text
-    var help string
-    switch m.mode {
-    case normalMode:
-        help = "\nNavigate: ↑/↓ • Change Day: ←/→ • New Task: n • Enter Time Slot: Enter • Current Time: T • Quit: q"
-    case taskSelectionMode:
-        help = "\nNavigate Tasks: ↑/↓ • Delete: dd • Exit Selection: Esc"
-    case taskCreationMode:
-        help = "\nTab: Switch fields • Enter: Save • Esc: Cancel"
-    }
-    
-    // Error message
-    var errorDisplay string
-    if m.errorMsg != "" && time.Since(m.errorTimer) < 3*time.Second {
-        errorStyle := lipgloss.NewStyle().
-            Foreground(lipgloss.Color("196")).
-            Margin(1)
-        errorDisplay = errorStyle.Render(m.errorMsg)
-    }
-    
-    return appStyle.Render(header + "\n" + slots + errorDisplay + form + help)
+    return m.appStyle().Render(header + "\n" + slots + errorDisplay + form + help)
 }
 func main() {
-    p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+    if len(os.Args) > 1 {
+        if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+            fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+            os.Exit(1)
+        }
+        return
+    }
+
+    p := tea.NewProgram(initialModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
     go func() {
         ticker := time.NewTicker(time.Minute)
         defer ticker.Stop()
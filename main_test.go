@@ -0,0 +1,202 @@
+package main
+
+import (
+    "path/filepath"
+    "testing"
+    "time"
+
+    "scheduler/db"
+)
+
+func openTestDB(t *testing.T) *db.DB {
+    t.Helper()
+    database, err := db.Open(filepath.Join(t.TempDir(), "scheduler.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { database.Close() })
+    return database
+}
+
+// testSettings returns the default settings with the schedule shown in loc.
+func testSettings(t *testing.T, loc *time.Location) settings {
+    t.Helper()
+    s, err := defaultConfig().settings()
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.location = loc
+    return s
+}
+
+func newYork(t *testing.T) *time.Location {
+    t.Helper()
+    loc, err := time.LoadLocation("America/New_York")
+    if err != nil {
+        t.Skipf("no zone data: %v", err)
+    }
+    return loc
+}
+
+func utc(hour, minute int, day string) time.Time {
+    d, _ := time.Parse("2006-01-02", day)
+    return d.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func TestGenerateTimeSlotsDST(t *testing.T) {
+    loc := newYork(t)
+    tests := []struct {
+        name        string
+        date        string
+        slotMinutes int
+        count       int
+        labels      map[int]string
+        clocks      map[int]clockChange
+    }{
+        {
+            name:        "spring forward, hourly",
+            date:        "2026-03-08",
+            slotMinutes: 60,
+            count:       24,
+            labels: map[int]string{
+                0:  "12:00 AM - 1:00 AM",
+                1:  "1:00 AM - 2:00 AM",
+                2:  "2:00 AM - 3:00 AM skipped (clocks go forward)",
+                3:  "3:00 AM - 4:00 AM",
+                23: "11:00 PM - 12:00 AM",
+            },
+            clocks: map[int]clockChange{1: clockNormal, 2: clockSkipped, 3: clockNormal},
+        },
+        {
+            name:        "spring forward, half-hourly",
+            date:        "2026-03-08",
+            slotMinutes: 30,
+            count:       47,
+            labels: map[int]string{
+                3: "1:30 AM - 2:00 AM",
+                4: "2:00 AM - 3:00 AM skipped (clocks go forward)",
+                5: "3:00 AM - 3:30 AM",
+            },
+            clocks: map[int]clockChange{4: clockSkipped, 5: clockNormal},
+        },
+        {
+            name:        "fall back, hourly",
+            date:        "2026-11-01",
+            slotMinutes: 60,
+            count:       25,
+            labels: map[int]string{
+                0:  "12:00 AM - 1:00 AM",
+                1:  "1:00 AM - 2:00 AM EDT",
+                2:  "1:00 AM - 2:00 AM EST (repeated)",
+                3:  "2:00 AM - 3:00 AM",
+                24: "11:00 PM - 12:00 AM",
+            },
+            clocks: map[int]clockChange{0: clockNormal, 1: clockFirstPass, 2: clockRepeated, 3: clockNormal},
+        },
+        {
+            name:        "fall back, half-hourly",
+            date:        "2026-11-01",
+            slotMinutes: 30,
+            count:       50,
+            labels: map[int]string{
+                2: "1:00 AM - 1:30 AM EDT",
+                3: "1:30 AM - 2:00 AM EDT",
+                4: "1:00 AM - 1:30 AM EST (repeated)",
+                5: "1:30 AM - 2:00 AM EST (repeated)",
+                6: "2:00 AM - 2:30 AM",
+            },
+            clocks: map[int]clockChange{
+                1: clockNormal, 2: clockFirstPass, 3: clockFirstPass,
+                4: clockRepeated, 5: clockRepeated, 6: clockNormal,
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            date, _ := time.ParseInLocation("2006-01-02", tt.date, loc)
+            slots := generateTimeSlots(date, tt.slotMinutes, fullDay, true)
+            if len(slots) != tt.count {
+                t.Fatalf("got %d slots, want %d", len(slots), tt.count)
+            }
+            for i, want := range tt.labels {
+                if got := formatTimeSlot(slots[i]); got != want {
+                    t.Errorf("slot %d: got %q, want %q", i, got, want)
+                }
+            }
+            for i, want := range tt.clocks {
+                if slots[i].Clock != want {
+                    t.Errorf("slot %d: got clock %d, want %d", i, slots[i].Clock, want)
+                }
+            }
+            // Slots cover the day without gaps or overlaps.
+            for i := 1; i < len(slots); i++ {
+                if !slots[i].StartTime.Equal(slots[i-1].EndTime) {
+                    t.Errorf("slot %d starts at %v, slot %d ends at %v", i, slots[i].StartTime, i-1, slots[i-1].EndTime)
+                }
+            }
+        })
+    }
+}
+
+func TestSlotIndexAtDST(t *testing.T) {
+    loc := newYork(t)
+    tests := []struct {
+        date string
+        at   time.Time
+        want int
+    }{
+        // 2026-03-08: 2:00 EST jumps to 3:00 EDT at 07:00 UTC.
+        {"2026-03-08", utc(6, 59, "2026-03-08"), 1},
+        {"2026-03-08", utc(7, 0, "2026-03-08"), 3},
+        {"2026-03-08", utc(13, 0, "2026-03-08"), 9},
+        {"2026-03-08", utc(4, 59, "2026-03-08"), -1},
+        {"2026-03-08", utc(3, 59, "2026-03-09"), 23},
+        // 2026-11-01: 2:00 EDT falls back to 1:00 EST at 06:00 UTC.
+        {"2026-11-01", utc(5, 30, "2026-11-01"), 1},
+        {"2026-11-01", utc(6, 30, "2026-11-01"), 2},
+        {"2026-11-01", utc(7, 0, "2026-11-01"), 3},
+        {"2026-11-01", utc(17, 0, "2026-11-01"), 13},
+        {"2026-11-01", utc(5, 0, "2026-11-02"), -1},
+    }
+    for _, tt := range tests {
+        date, _ := time.ParseInLocation("2006-01-02", tt.date, loc)
+        slots := generateTimeSlots(date, 60, fullDay, true)
+        if got := slotIndexAt(slots, tt.at); got != tt.want {
+            t.Errorf("%s: slotIndexAt(%v) = %d, want %d", tt.date, tt.at.In(loc), got, tt.want)
+        }
+    }
+}
+
+func TestClockShiftAndBoundaryDST(t *testing.T) {
+    loc := newYork(t)
+    springForward := utc(7, 0, "2026-03-08")
+    fallBack := utc(6, 0, "2026-11-01")
+
+    if got := clockShift(springForward.In(loc)); got != time.Hour {
+        t.Errorf("clockShift at spring forward = %v, want 1h", got)
+    }
+    if got := clockShift(fallBack.In(loc)); got != -time.Hour {
+        t.Errorf("clockShift at fall back = %v, want -1h", got)
+    }
+    if got := clockShift(springForward.Add(time.Hour).In(loc)); got != 0 {
+        t.Errorf("clockShift an hour later = %v, want 0", got)
+    }
+
+    tests := []struct {
+        from        time.Time
+        slotMinutes int
+        want        time.Time
+    }{
+        {utc(6, 30, "2026-03-08"), 60, springForward},
+        {utc(6, 45, "2026-03-08"), 15, springForward},
+        {springForward, 60, springForward.Add(time.Hour)},
+        {utc(5, 30, "2026-11-01"), 60, fallBack},
+        {fallBack, 30, fallBack.Add(30 * time.Minute)},
+    }
+    for _, tt := range tests {
+        if got := nextSlotBoundary(tt.from.In(loc), tt.slotMinutes); !got.Equal(tt.want) {
+            t.Errorf("nextSlotBoundary(%v, %d) = %v, want %v", tt.from.In(loc), tt.slotMinutes, got.In(loc), tt.want.In(loc))
+        }
+    }
+}
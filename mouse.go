@@ -0,0 +1,104 @@
+package main
+
+import (
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+)
+
+const doubleClickInterval = 400 * time.Millisecond
+
+type click struct {
+    slot int
+    at   time.Time
+}
+
+// hitTest maps a screen position to the slot and task rendered there, using
+// the same layout as View. task is -1 when the position is on the slot box.
+func (m model) hitTest(x, y int) (slot, task int, ok bool) {
+    // Border and padding of the app box come before the content.
+    left, right := 3, m.appWidth()-3
+    if x < left || x >= right {
+        return 0, 0, false
+    }
+
+    header, _, _, _ := m.chrome()
+    line := y - 2 - lipgloss.Height(header)
+    if line < 0 {
+        return 0, 0, false
+    }
+
+    top, bottom := m.visibleRange()
+    for i := top; i <= bottom; i++ {
+        if line < slotBoxHeight {
+            return i, -1, true
+        }
+        line -= slotBoxHeight
+        if line < len(m.timeSlots[i].Tasks) {
+            return i, line, true
+        }
+        line -= len(m.timeSlots[i].Tasks)
+    }
+    return 0, 0, false
+}
+
+func (m *model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+    if m.mode != normalMode && m.mode != taskSelectionMode {
+        return nil
+    }
+
+    switch msg.Button {
+    case tea.MouseButtonWheelUp, tea.MouseButtonWheelDown:
+        delta := 1
+        if msg.Button == tea.MouseButtonWheelUp {
+            delta = -1
+        }
+        cursor := m.cursor
+        m.scrollViewport(delta)
+        // The selected task, a pending delete and the history pane all
+        // belong to the old slot, so scrolling it away ends the selection.
+        if m.mode == taskSelectionMode && m.cursor != cursor {
+            m.mode = normalMode
+            m.taskCursor = 0
+            m.deletePending = false
+            m.showHistory = false
+        }
+        return nil
+    case tea.MouseButtonLeft:
+        if msg.Action != tea.MouseActionPress {
+            return nil
+        }
+    default:
+        return nil
+    }
+
+    slot, task, ok := m.hitTest(msg.X, msg.Y)
+    if !ok {
+        return nil
+    }
+
+    now := time.Now()
+    double := m.lastClick.slot == slot && now.Sub(m.lastClick.at) < doubleClickInterval
+    m.lastClick = click{slot: slot, at: now}
+
+    m.cursor = slot
+    m.deletePending = false
+    m.updateViewport()
+
+    if task >= 0 {
+        m.mode = taskSelectionMode
+        m.taskCursor = task
+        m.loadHistory()
+        return nil
+    }
+
+    m.mode = normalMode
+    m.taskCursor = 0
+    m.showHistory = false
+    if double {
+        m.lastClick = click{}
+        return m.openTaskForm()
+    }
+    return nil
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+// notesConfig describes a notes vault with one Markdown file per day.
+// Filename and frontmatter values may use {date}, {year}, {month}, {day} and
+// {weekday}; frontmatter values are written as they are, so a YAML list can
+// be given as "[a, b]".
+type notesConfig struct {
+    Dir         string            `toml:"dir"`
+    Filename    string            `toml:"filename"`
+    Frontmatter map[string]string `toml:"frontmatter"`
+}
+
+var noteTokenPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+var noteTokens = map[string]string{
+    "{date}":    "2006-01-02",
+    "{year}":    "2006",
+    "{month}":   "01",
+    "{day}":     "02",
+    "{weekday}": "Monday",
+}
+
+func (c notesConfig) validate() error {
+    if c.Filename == "" {
+        return fmt.Errorf("filename: must not be empty")
+    }
+    for _, token := range noteTokenPattern.FindAllString(c.Filename, -1) {
+        if _, ok := noteTokens[token]; !ok {
+            return fmt.Errorf("filename: unknown placeholder %s", token)
+        }
+    }
+    // {day} alone repeats every month, and an old note would then be read
+    // back into the wrong day.
+    dated := strings.Contains(c.Filename, "{date}") ||
+        (strings.Contains(c.Filename, "{year}") && strings.Contains(c.Filename, "{month}") && strings.Contains(c.Filename, "{day}"))
+    if !dated {
+        return fmt.Errorf("filename: %q needs {date}, or {year}, {month} and {day}, so each day gets its own note", c.Filename)
+    }
+    if filepath.IsAbs(c.Filename) || strings.Contains(c.Filename, "..") {
+        return fmt.Errorf("filename: %q must stay inside dir", c.Filename)
+    }
+    return nil
+}
+
+func expandNoteTemplate(template string, date time.Time) string {
+    return noteTokenPattern.ReplaceAllStringFunc(template, func(token string) string {
+        if layout, ok := noteTokens[token]; ok {
+            return date.Format(layout)
+        }
+        return token
+    })
+}
+
+// The schedule goes between these markers so the rest of a note is left to
+// its author and exporting again only replaces the list.
+const (
+    noteStart = "<!-- scheduler -->"
+    noteEnd   = "<!-- /scheduler -->"
+)
+
+var noteLinePattern = regexp.MustCompile(`^\s*[-*] \[([ xX])\] (\d{1,2}):(\d{2}) (.+) \((\d+)m\)\s*$`)
+
+func noteLine(t db.Task, loc *time.Location) string {
+    box := " "
+    if t.Done {
+        box = "x"
+    }
+    return fmt.Sprintf("- [%s] %s %s (%dm)", box, t.StartsAt.In(loc).Format("15:04"), t.Title, t.Duration)
+}
+
+func noteSection(tasks []db.Task, loc *time.Location) string {
+    lines := []string{noteStart}
+    for _, t := range tasks {
+        lines = append(lines, noteLine(t, loc))
+    }
+    return strings.Join(append(lines, noteEnd), "\n")
+}
+
+// noteContent returns existing with its schedule section replaced by
+// section, or a new note when there is none yet.
+func (c notesConfig) noteContent(existing string, section string, date time.Time) string {
+    if existing == "" {
+        if len(c.Frontmatter) == 0 {
+            return section + "\n"
+        }
+        keys := make([]string, 0, len(c.Frontmatter))
+        for k := range c.Frontmatter {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+        lines := []string{"---"}
+        for _, k := range keys {
+            lines = append(lines, k+": "+expandNoteTemplate(c.Frontmatter[k], date))
+        }
+        return strings.Join(append(lines, "---", "", section), "\n") + "\n"
+    }
+
+    start := strings.Index(existing, noteStart)
+    end := strings.Index(existing, noteEnd)
+    if start >= 0 && end > start {
+        return existing[:start] + section + existing[end+len(noteEnd):]
+    }
+    return strings.TrimRight(existing, "\n") + "\n\n" + section + "\n"
+}
+
+func (c notesConfig) notePath(date time.Time) string {
+    return filepath.Join(c.Dir, expandNoteTemplate(c.Filename, date))
+}
+
+// exportNote writes date's schedule into its note and returns the note's
+// path, or "" when there was nothing to write.
+func exportNote(database *db.DB, c notesConfig, date time.Time) (string, error) {
+    tasks, err := database.GetTasksForDate(date)
+    if err != nil {
+        return "", err
+    }
+
+    path := c.notePath(date)
+    existing, err := os.ReadFile(path)
+    if err != nil && !errors.Is(err, os.ErrNotExist) {
+        return "", err
+    }
+    // Days with nothing planned do not get a note of their own.
+    if len(existing) == 0 && len(tasks) == 0 {
+        return "", nil
+    }
+    content := c.noteContent(string(existing), noteSection(tasks, date.Location()), date)
+    if content == string(existing) {
+        return path, nil
+    }
+
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return "", err
+    }
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+        return "", err
+    }
+    return path, os.Rename(tmp, path)
+}
+
+type noteImport struct {
+    done, undone, unmatched int
+}
+
+// noteEntry is a checkbox line of a note, matched to its task.
+type noteEntry struct {
+    path string
+    line int
+    id   int64
+    done bool
+}
+
+func (e noteEntry) taskID() int64 {
+    return e.id
+}
+
+func (e noteEntry) where() string {
+    return fmt.Sprintf("%s: line %d", e.path, e.line+1)
+}
+
+// task only changes Done; the rest of the line is how the task is found.
+func (e noteEntry) task(t db.Task, s settings) (db.Task, error) {
+    t.Done = e.done
+    return t, nil
+}
+
+// noteEntries reads the checkboxes of a day's note. A line belongs to the
+// task starting at its time with its title.
+func noteEntries(database *db.DB, c notesConfig, date time.Time, result *noteImport) ([]fileEntry, error) {
+    path := c.notePath(date)
+    data, err := os.ReadFile(path)
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    tasks, err := database.GetTasksForDate(date)
+    if err != nil {
+        return nil, err
+    }
+
+    var entries []fileEntry
+    for i, line := range strings.Split(string(data), "\n") {
+        match := noteLinePattern.FindStringSubmatch(line)
+        if match == nil {
+            continue
+        }
+        hour, _ := strconv.Atoi(match[2])
+        minute, _ := strconv.Atoi(match[3])
+        clock := fmt.Sprintf("%02d:%02d", hour, minute)
+        done := match[1] != " "
+
+        found := false
+        for _, t := range tasks {
+            if t.Title != match[4] || t.StartsAt.In(date.Location()).Format("15:04") != clock {
+                continue
+            }
+            found = true
+            if t.Done == done {
+                continue
+            }
+            entries = append(entries, noteEntry{path: path, line: i, id: t.ID, done: done})
+            if done {
+                result.done++
+            } else {
+                result.undone++
+            }
+        }
+        if !found {
+            result.unmatched++
+        }
+    }
+    return entries, nil
+}
+
+// importNotes reads the checkboxes of days notes from first back into Done.
+// Every note is read before anything is written, and the writes happen in
+// one transaction, so a note that cannot be read leaves all of them
+// unimported.
+func importNotes(database *db.DB, s settings, first time.Time, days int) (noteImport, error) {
+    var result noteImport
+    var entries []fileEntry
+    for i := 0; i < days; i++ {
+        date := first.AddDate(0, 0, i)
+        dayEntries, err := noteEntries(database, s.notes, date, &result)
+        if err != nil {
+            return result, fmt.Errorf("%s: %v", s.notes.notePath(date), err)
+        }
+        entries = append(entries, dayEntries...)
+    }
+    return result, applyImport(database, entries, 0, s, &importResult{}, nil)
+}
+
+func runNotes(args []string) error {
+    usage := "usage: scheduler notes export|import [-date DATE] [-days N]"
+    if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+        return errors.New(usage)
+    }
+    fs := flag.NewFlagSet("notes "+args[0], flag.ContinueOnError)
+    var days dayRange
+    days.register(fs)
+    if err := fs.Parse(args[1:]); err != nil {
+        return err
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    if s.notes.Dir == "" {
+        return errors.New("notes.dir is not set in the config file")
+    }
+    first, err := days.first(s.location)
+    if err != nil {
+        return err
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    if args[0] == "import" {
+        result, err := importNotes(database, s, first, days.days)
+        if err != nil {
+            return err
+        }
+        fmt.Printf("marked %d done and %d not done, %d lines matched no task\n", result.done, result.undone, result.unmatched)
+        return nil
+    }
+
+    for i := 0; i < days.days; i++ {
+        date := first.AddDate(0, 0, i)
+        path, err := exportNote(database, s.notes, date)
+        if err != nil {
+            return fmt.Errorf("%s: %v", s.notes.notePath(date), err)
+        }
+        if path != "" {
+            fmt.Println(path)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestNotesFilenameValidate(t *testing.T) {
+    tests := []struct {
+        filename string
+        ok       bool
+    }{
+        {"{date}.md", true},
+        {"{year}/{month}/{day}.md", true},
+        {"daily/{year}-{month}-{day} {weekday}.md", true},
+        {"{day}.md", false},
+        {"{month}-{day}.md", false},
+        {"{year}-{day}.md", false},
+        {"{weekday}.md", false},
+        {"{date}-{hour}.md", false},
+        {"../{date}.md", false},
+        {"", false},
+    }
+    for _, tt := range tests {
+        err := notesConfig{Filename: tt.filename}.validate()
+        if (err == nil) != tt.ok {
+            t.Errorf("validate(%q) = %v, want ok %v", tt.filename, err, tt.ok)
+        }
+    }
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+const orgStamp = "2006-01-02 Mon 15:04"
+
+var (
+    orgHeadingPattern   = regexp.MustCompile(`^(\*+)\s+(?:(TODO|DONE)\s+)?(?:\[#[A-Za-z0-9]\]\s*)?(.*?)(?:\s+:(?:[\w@#%]+:)+)?\s*$`)
+    orgScheduledPattern = regexp.MustCompile(`SCHEDULED:\s*<(\d{4}-\d{2}-\d{2})(?:\s+[^\s\d>]+)?(?:\s+(\d{1,2}:\d{2})(?:-(\d{1,2}:\d{2}))?)?[^>]*>`)
+    orgPropertyPattern  = regexp.MustCompile(`^\s*:([A-Za-z_-]+):\s*(.*?)\s*$`)
+    orgEffortPattern    = regexp.MustCompile(`^(?:(\d+):(\d{2})|(\d+)\s*(?:min)?)$`)
+)
+
+// orgEntry is an Org heading that carries a task: a TODO or DONE keyword and
+// a SCHEDULED timestamp with a time. Line numbers index into the file so IDs
+// can be written back to new headings.
+type orgEntry struct {
+    line     int
+    planning int
+    drawer   int
+    indent   string
+    title    string
+    done     bool
+    date     string
+    clock    string
+    end      string
+    effort   int
+    id       int64
+}
+
+func orgEffort(minutes int) string {
+    return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+func exportOrg(tasks []db.Task, loc *time.Location) string {
+    var b strings.Builder
+    b.WriteString("#+TITLE: Schedule\n")
+    for _, t := range tasks {
+        keyword := "TODO"
+        if t.Done {
+            keyword = "DONE"
+        }
+        fmt.Fprintf(&b, "\n* %s %s\n", keyword, t.Title)
+        fmt.Fprintf(&b, "  SCHEDULED: <%s>\n", t.StartsAt.In(loc).Format(orgStamp))
+        b.WriteString("  :PROPERTIES:\n")
+        fmt.Fprintf(&b, "  :EFFORT:   %s\n", orgEffort(t.Duration))
+        fmt.Fprintf(&b, "  :SCHEDULER_ID: %d\n", t.ID)
+        b.WriteString("  :END:\n")
+    }
+    return b.String()
+}
+
+// parseOrg finds the task headings in an Org file. Headings without a
+// keyword, such as day headings, or without a scheduled time are skipped.
+// Priority cookies and tags are not part of the title, so adding them in
+// Emacs does not rename the task.
+func parseOrg(lines []string) ([]orgEntry, int) {
+    var entries []orgEntry
+    skipped := 0
+    var cur *orgEntry
+    flush := func() {
+        if cur == nil {
+            return
+        }
+        if cur.clock != "" {
+            entries = append(entries, *cur)
+        } else {
+            skipped++
+        }
+        cur = nil
+    }
+
+    inDrawer := false
+    for i, line := range lines {
+        if m := orgHeadingPattern.FindStringSubmatch(line); m != nil {
+            flush()
+            inDrawer = false
+            if m[2] != "" {
+                cur = &orgEntry{
+                    line:     i,
+                    planning: -1,
+                    drawer:   -1,
+                    indent:   strings.Repeat(" ", len(m[1])+1),
+                    title:    m[3],
+                    done:     m[2] == "DONE",
+                    effort:   -1,
+                }
+            }
+            continue
+        }
+        if cur == nil {
+            continue
+        }
+
+        if m := orgScheduledPattern.FindStringSubmatch(line); m != nil && cur.planning < 0 {
+            cur.planning = i
+            cur.date, cur.clock, cur.end = m[1], m[2], m[3]
+            continue
+        }
+        trimmed := strings.TrimSpace(line)
+        switch {
+        case trimmed == ":PROPERTIES:":
+            inDrawer = true
+            cur.drawer = i
+            continue
+        case trimmed == ":END:":
+            inDrawer = false
+            continue
+        }
+        if !inDrawer {
+            continue
+        }
+        m := orgPropertyPattern.FindStringSubmatch(line)
+        if m == nil {
+            continue
+        }
+        switch strings.ToUpper(m[1]) {
+        case "EFFORT":
+            if e := orgEffortPattern.FindStringSubmatch(m[2]); e != nil {
+                if e[3] != "" {
+                    cur.effort, _ = strconv.Atoi(e[3])
+                } else {
+                    hours, _ := strconv.Atoi(e[1])
+                    minutes, _ := strconv.Atoi(e[2])
+                    cur.effort = hours*60 + minutes
+                }
+            }
+        case "SCHEDULER_ID":
+            cur.id, _ = strconv.ParseInt(m[2], 10, 64)
+        }
+    }
+    flush()
+    return entries, skipped
+}
+
+func (e orgEntry) taskID() int64 {
+    return e.id
+}
+
+func (e orgEntry) where() string {
+    return fmt.Sprintf("line %d", e.line+1)
+}
+
+// task turns an entry into the task it describes, on top of t.
+func (e orgEntry) task(t db.Task, s settings) (db.Task, error) {
+    loc := s.location
+    if t.ID != 0 {
+        loc = t.StartsAt.Location()
+    }
+    startsAt, err := time.ParseInLocation("2006-01-02 15:04", e.date+" "+e.clock, s.location)
+    if err != nil {
+        return t, err
+    }
+    t.StartsAt = startsAt.In(loc)
+    t.Title = e.title
+    t.Done = e.done
+
+    switch {
+    case e.effort >= 0:
+        t.Duration = e.effort
+    case e.end != "":
+        end, err := time.ParseInLocation("2006-01-02 15:04", e.date+" "+e.end, s.location)
+        if err != nil {
+            return t, err
+        }
+        t.Duration = int(end.Sub(startsAt).Minutes())
+    case t.ID == 0:
+        t.Duration = s.defaultDuration
+    }
+    return t, validateTask(t.Title, t.Duration, s)
+}
+
+// importOrg applies the headings in lines to the database. save gets lines
+// with a SCHEDULER_ID added to the headings that became new tasks.
+func importOrg(database *db.DB, lines []string, calendar int64, s settings, save func(out []string) error) (importResult, error) {
+    var result importResult
+    parsed, skipped := parseOrg(lines)
+    result.skipped = skipped
+    entries := make([]fileEntry, len(parsed))
+    for i, e := range parsed {
+        entries[i] = e
+    }
+
+    err := applyImport(database, entries, calendar, s, &result, func(ids []int64) error {
+        insert := make(map[int][]string)
+        for i, e := range parsed {
+            if ids[i] == 0 {
+                continue
+            }
+            idLine := fmt.Sprintf("%s:SCHEDULER_ID: %d", e.indent, ids[i])
+            switch {
+            case e.drawer >= 0:
+                insert[e.drawer] = append(insert[e.drawer], idLine)
+            case e.planning >= 0:
+                insert[e.planning] = append(insert[e.planning], e.indent+":PROPERTIES:", idLine, e.indent+":END:")
+            default:
+                insert[e.line] = append(insert[e.line], e.indent+":PROPERTIES:", idLine, e.indent+":END:")
+            }
+        }
+
+        out := make([]string, 0, len(lines)+3*len(insert))
+        for i, line := range lines {
+            out = append(out, line)
+            out = append(out, insert[i]...)
+        }
+        if save == nil {
+            return nil
+        }
+        return save(out)
+    })
+    return result, err
+}
+
+var orgFormat = fileFormat{
+    name:    "org",
+    entries: "headings",
+    export:  exportOrg,
+    apply:   importOrg,
+}
+
+func runOrg(args []string) error {
+    return orgFormat.run(args)
+}
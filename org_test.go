@@ -0,0 +1,109 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseOrgHeadings(t *testing.T) {
+    tests := []struct {
+        heading string
+        title   string
+        done    bool
+    }{
+        {"* TODO Call plumber", "Call plumber", false},
+        {"* DONE Call plumber", "Call plumber", true},
+        {"** TODO [#A] Call plumber", "Call plumber", false},
+        {"* TODO Call plumber  :home:phone:", "Call plumber", false},
+        {"* DONE [#B] Call plumber :home:", "Call plumber", true},
+        {"* TODO Ratio 3:2 is fine", "Ratio 3:2 is fine", false},
+        {"* TODO Meet at 10:30", "Meet at 10:30", false},
+    }
+    for _, tt := range tests {
+        entries, _ := parseOrg([]string{tt.heading, "  SCHEDULED: <2026-10-19 Mon 09:30>"})
+        if len(entries) != 1 {
+            t.Errorf("%q: got %d entries, want 1", tt.heading, len(entries))
+            continue
+        }
+        if e := entries[0]; e.title != tt.title || e.done != tt.done {
+            t.Errorf("%q: got title %q done %v, want %q %v", tt.heading, e.title, e.done, tt.title, tt.done)
+        }
+    }
+}
+
+func TestOrgRoundTrip(t *testing.T) {
+    database := openTestDB(t)
+    s := testSettings(t, newYork(t))
+    day := time.Date(2026, 10, 19, 0, 0, 0, 0, s.location)
+
+    standup, err := database.SaveTask(1, day.Add(9*time.Hour+30*time.Minute), "Standup", 15)
+    if err != nil {
+        t.Fatal(err)
+    }
+    review, err := database.SaveTask(1, day.Add(14*time.Hour), "Review PRs", 60)
+    if err != nil {
+        t.Fatal(err)
+    }
+    tasks, err := database.GetTasksBetween(day, day.AddDate(0, 0, 1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    exported := exportOrg(tasks, s.location)
+
+    // Importing an untouched export changes nothing.
+    lines := strings.Split(exported, "\n")
+    if result, err := importOrg(database, lines, 1, s, nil); err != nil {
+        t.Fatal(err)
+    } else if result != (importResult{unchanged: 2}) {
+        t.Fatalf("untouched import: %+v", result)
+    }
+
+    // Agenda edits: a priority and tags, which are not renames, a finished
+    // task, a moved task and a new heading.
+    edited := strings.NewReplacer(
+        "* TODO Standup", "* DONE [#A] Standup    :work:daily:",
+        "* TODO Review PRs", "* TODO Review PRs :work:",
+        "<2026-10-19 Mon 14:00>", "<2026-10-20 Tue 15:30>",
+    ).Replace(exported)
+    edited += "\n* TODO [#B] Water plants :home:\n  SCHEDULED: <2026-10-19 Mon 18:00-18:20>\n"
+    lines = strings.Split(edited, "\n")
+
+    var out []string
+    result, err := importOrg(database, lines, 1, s, func(lines []string) error {
+        out = lines
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result != (importResult{created: 1, updated: 2}) {
+        t.Fatalf("edited import: %+v", result)
+    }
+
+    got, _ := database.GetTask(standup)
+    if got.Title != "Standup" || !got.Done || got.Duration != 15 {
+        t.Errorf("standup: %q done %v %dm", got.Title, got.Done, got.Duration)
+    }
+    got, _ = database.GetTask(review)
+    if want := time.Date(2026, 10, 20, 15, 30, 0, 0, s.location); got.Title != "Review PRs" || !got.StartsAt.Equal(want) {
+        t.Errorf("review: %q at %v, want at %v", got.Title, got.StartsAt, want)
+    }
+    created, err := database.GetTasksBetween(day.Add(18*time.Hour), day.Add(19*time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(created) != 1 || created[0].Title != "Water plants" || created[0].Duration != 20 {
+        t.Fatalf("new heading became %+v", created)
+    }
+
+    // The new heading got its ID, so importing again updates nothing.
+    if !strings.Contains(strings.Join(out, "\n"), ":SCHEDULER_ID: ") {
+        t.Fatalf("no ID written back:\n%s", strings.Join(out, "\n"))
+    }
+    if result, err := importOrg(database, out, 1, s, nil); err != nil {
+        t.Fatal(err)
+    } else if result != (importResult{unchanged: 3}) {
+        t.Errorf("second import: %+v", result)
+    }
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/charmbracelet/bubbles/textinput"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// quickTask is what a quick-add line parses to.
+type quickTask struct {
+    title    string
+    startsAt time.Time
+    duration int
+}
+
+type quickAddForm struct {
+    input textinput.Model
+    err   string
+}
+
+var (
+    clockPattern    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+    durationPattern = regexp.MustCompile(`^(?:(\d+)h(?:(\d+)m?)?|(\d+)(?:m|min|mins))$`)
+)
+
+var weekdays = map[string]time.Weekday{
+    "sun": time.Sunday, "sunday": time.Sunday,
+    "mon": time.Monday, "monday": time.Monday,
+    "tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+    "wed": time.Wednesday, "wednesday": time.Wednesday,
+    "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+    "fri": time.Friday, "friday": time.Friday,
+    "sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// parseDateWords reads a date from the start of words: today, tomorrow,
+// yesterday, a weekday name optionally preceded by "next", or an ISO date.
+// It returns the day and how many words it used, or 0 if there is no date.
+func parseDateWords(words []string, today time.Time) (time.Time, int) {
+    if len(words) == 0 {
+        return time.Time{}, 0
+    }
+    today = dayStart(today)
+
+    word := strings.ToLower(words[0])
+    switch word {
+    case "today":
+        return today, 1
+    case "tomorrow", "tmr":
+        return today.AddDate(0, 0, 1), 1
+    case "yesterday":
+        return today.AddDate(0, 0, -1), 1
+    case "next":
+        if len(words) > 1 {
+            if day, ok := weekdays[strings.ToLower(words[1])]; ok {
+                return nextWeekday(today, day, true), 2
+            }
+        }
+        return time.Time{}, 0
+    }
+
+    if day, ok := weekdays[word]; ok {
+        return nextWeekday(today, day, false), 1
+    }
+    if date, err := time.ParseInLocation("2006-01-02", word, today.Location()); err == nil {
+        return date, 1
+    }
+    return time.Time{}, 0
+}
+
+// nextWeekday returns the first day on or after today that falls on day, or
+// strictly after today when skipToday is set.
+func nextWeekday(today time.Time, day time.Weekday, skipToday bool) time.Time {
+    days := (int(day) - int(today.Weekday()) + 7) % 7
+    if days == 0 && skipToday {
+        days = 7
+    }
+    return today.AddDate(0, 0, days)
+}
+
+// parseClockWord reads a time of day such as 9am, 9:30pm, 14:00 or 12:30,
+// returning minutes after midnight. A bare hour is only accepted with am/pm
+// or when the caller already saw "at".
+func parseClockWord(word string, bareHour bool) (int, bool) {
+    match := clockPattern.FindStringSubmatch(strings.ToLower(word))
+    if match == nil || (match[2] == "" && match[3] == "" && !bareHour) {
+        return 0, false
+    }
+
+    hour, _ := strconv.Atoi(match[1])
+    minute := 0
+    if match[2] != "" {
+        minute, _ = strconv.Atoi(match[2])
+    }
+    if minute > 59 {
+        return 0, false
+    }
+
+    switch match[3] {
+    case "am", "pm":
+        if hour < 1 || hour > 12 {
+            return 0, false
+        }
+        hour %= 12
+        if match[3] == "pm" {
+            hour += 12
+        }
+    default:
+        if hour > 23 {
+            return 0, false
+        }
+    }
+    return hour*60 + minute, true
+}
+
+// parseDurationWord reads a duration such as 45m, 90min, 2h or 1h30.
+func parseDurationWord(word string) (int, bool) {
+    match := durationPattern.FindStringSubmatch(strings.ToLower(word))
+    if match == nil {
+        return 0, false
+    }
+    if match[3] != "" {
+        minutes, _ := strconv.Atoi(match[3])
+        return minutes, true
+    }
+    hours, _ := strconv.Atoi(match[1])
+    minutes, _ := strconv.Atoi(match[2])
+    return hours*60 + minutes, true
+}
+
+// parseQuickAdd pulls a date, time and duration out of free text and keeps
+// the rest as the title. Missing parts fall back to the day and slot the
+// cursor is on and the default duration.
+func parseQuickAdd(text string, slot time.Time, today time.Time, s settings) (quickTask, error) {
+    var title []string
+    var date time.Time
+    // -1 is unset, so an explicit 0m reaches validateTask.
+    minute, duration := -1, -1
+
+    words := strings.Fields(text)
+    for i := 0; i < len(words); {
+        // "on", "at" and "for" are only dropped when what follows them parses
+        // as a date, a time or a duration respectively.
+        lead := strings.ToLower(words[i])
+        j := i
+        if (lead == "on" || lead == "at" || lead == "for") && i+1 < len(words) {
+            j++
+        }
+        rest := words[j:]
+
+        if lead != "at" && lead != "for" && date.IsZero() {
+            if d, n := parseDateWords(rest, today); n > 0 {
+                date = d
+                i = j + n
+                continue
+            }
+        }
+        if lead != "on" && lead != "for" && minute < 0 {
+            if t, ok := parseClockWord(rest[0], lead == "at"); ok {
+                n := 1
+                // "9 am" is written as two words just as often as one.
+                if len(rest) > 1 && clockSuffix(rest[1]) && strings.IndexAny(rest[0], "apAP") < 0 {
+                    if t, ok = parseClockWord(rest[0]+rest[1], false); !ok {
+                        return quickTask{}, fmt.Errorf("invalid time %q", rest[0]+" "+rest[1])
+                    }
+                    n++
+                }
+                minute = t
+                i = j + n
+                continue
+            }
+        }
+        if lead != "on" && lead != "at" && duration < 0 {
+            if d, ok := parseDurationWord(rest[0]); ok {
+                duration = d
+                i = j + 1
+                continue
+            }
+        }
+
+        title = append(title, words[i])
+        i++
+    }
+
+    task := quickTask{title: strings.Join(title, " "), duration: duration}
+    if task.title == "" {
+        return task, fmt.Errorf("add a title")
+    }
+    if task.duration < 0 {
+        task.duration = s.defaultDuration
+    }
+    if err := validateTask(task.title, task.duration, s); err != nil {
+        return task, err
+    }
+
+    if date.IsZero() {
+        date = slot
+    }
+    if minute < 0 {
+        minute = slot.Hour()*60 + slot.Minute()
+    }
+    task.startsAt = slotStartTime(date, minute)
+    return task, nil
+}
+
+func clockSuffix(word string) bool {
+    word = strings.ToLower(word)
+    return word == "am" || word == "pm"
+}
+
+func initialQuickAdd(s settings) quickAddForm {
+    ti := textinput.New()
+    ti.Placeholder = "lunch with Sam tomorrow 12:30 for 1h"
+    ti.CharLimit = s.titleCharLimit + 64
+    ti.Focus()
+    return quickAddForm{input: ti}
+}
+
+func (m *model) openQuickAdd() tea.Cmd {
+    m.quickAdd = initialQuickAdd(m.settings)
+    m.mode = quickAddMode
+    m.applyLayout()
+    return textinput.Blink
+}
+
+func (m model) quickAddTask() (quickTask, error) {
+    slot := m.currentDate
+    if m.cursor >= 0 && m.cursor < len(m.timeSlots) {
+        slot = m.timeSlots[m.cursor].StartTime.In(m.settings.location)
+    }
+    return parseQuickAdd(m.quickAdd.input.Value(), slot, m.now(), m.settings)
+}
+
+// saveQuickAdd stores the parsed task and moves the schedule to it.
+func (m *model) saveQuickAdd() {
+    task, err := m.quickAddTask()
+    if err != nil {
+        m.quickAdd.err = err.Error()
+        return
+    }
+    if _, err := m.db.SaveTask(m.calendar, task.startsAt, task.title, task.duration); err != nil {
+        m.quickAdd.err = "Failed to save task"
+        return
+    }
+
+    m.mode = normalMode
+    m.applyLayout()
+    m.currentDate = task.startsAt
+    m.cursor = -1
+    m.regenerateSlots()
+    if i := slotIndexAt(m.timeSlots, task.startsAt); i >= 0 {
+        m.cursor = i
+    }
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+    m.centerOnCursor()
+}
+
+func (m model) quickAddView() string {
+    preview := ""
+    if m.quickAdd.input.Value() != "" {
+        if task, err := m.quickAddTask(); err != nil {
+            preview = "→ " + err.Error()
+        } else {
+            preview = fmt.Sprintf("→ %s (%dm) · %s",
+                task.startsAt.Format("Mon Jan 2, 3:04 PM"), task.duration, task.title)
+        }
+    }
+    if m.quickAdd.err != "" {
+        preview = m.quickAdd.err
+    }
+    return formStyle.Render(fmt.Sprintf(
+        "Quick Add\n\n%s\n\n%s",
+        m.quickAdd.input.View(),
+        truncate(preview, m.contentWidth()-4),
+    ))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+    "testing"
+    "time"
+)
+
+func TestParseQuickAddDuration(t *testing.T) {
+    s := testSettings(t, time.UTC)
+    slot := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+    task, err := parseQuickAdd("Call the bank for 45m", slot, slot, s)
+    if err != nil || task.title != "Call the bank" || task.duration != 45 {
+        t.Errorf("for 45m: got %+v, %v", task, err)
+    }
+    task, err = parseQuickAdd("Call the bank", slot, slot, s)
+    if err != nil || task.duration != s.defaultDuration {
+        t.Errorf("no duration: got %+v, %v, want the default %dm", task, err, s.defaultDuration)
+    }
+    if task, err := parseQuickAdd("Call the bank for 0m", slot, slot, s); err == nil {
+        t.Errorf("for 0m: got %+v, want an error", task)
+    }
+}
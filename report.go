@@ -0,0 +1,332 @@
+package main
+
+import (
+    "bytes"
+    "encoding/csv"
+    "flag"
+    "fmt"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+type reportRow struct {
+    label    string
+    planned  int
+    tracked  int
+    tasks    int
+    done     int
+    overruns int
+}
+
+type report struct {
+    from  time.Time
+    to    time.Time
+    total reportRow
+    days  []reportRow
+    tags  []reportRow
+}
+
+func (r *reportRow) add(task db.Task) {
+    tracked := int(task.Tracked.Minutes())
+    r.planned += task.Duration
+    r.tracked += tracked
+    r.tasks++
+    if task.Done {
+        r.done++
+    }
+    if tracked > task.Duration {
+        r.overruns++
+    }
+}
+
+func (r reportRow) completion() int {
+    if r.tasks == 0 {
+        return 0
+    }
+    return r.done * 100 / r.tasks
+}
+
+func weekStart(date time.Time) time.Time {
+    offset := (int(date.Weekday()) + 6) % 7
+    return date.AddDate(0, 0, -offset)
+}
+
+func reportRange(date time.Time, week bool) (time.Time, time.Time) {
+    if !week {
+        return date, date
+    }
+    from := weekStart(date)
+    return from, from.AddDate(0, 0, 6)
+}
+
+func buildReport(tasks []db.Task, from, to time.Time) report {
+    r := report{from: from, to: to, total: reportRow{label: "Total"}}
+
+    // Days are found by index, since appending may move r.days.
+    days := make(map[string]int)
+    for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+        key := d.Format("2006-01-02")
+        days[key] = len(r.days)
+        r.days = append(r.days, reportRow{label: key})
+    }
+
+    tags := make(map[string]*reportRow)
+    for _, task := range tasks {
+        r.total.add(task)
+        if i, ok := days[task.StartsAt.In(from.Location()).Format("2006-01-02")]; ok {
+            r.days[i].add(task)
+        }
+
+        taskTags := task.Tags()
+        if len(taskTags) == 0 {
+            taskTags = []string{""}
+        }
+        for _, tag := range taskTags {
+            row, ok := tags[tag]
+            if !ok {
+                label := "#" + tag
+                switch {
+                case tag == "":
+                    label = "(untagged)"
+                case tag[0] == '+' || tag[0] == '@':
+                    label = tag
+                }
+                row = &reportRow{label: label}
+                tags[tag] = row
+            }
+            row.add(task)
+        }
+    }
+
+    for _, row := range tags {
+        r.tags = append(r.tags, *row)
+    }
+    sort.Slice(r.tags, func(i, j int) bool {
+        if r.tags[i].planned != r.tags[j].planned {
+            return r.tags[i].planned > r.tags[j].planned
+        }
+        return r.tags[i].label < r.tags[j].label
+    })
+
+    return r
+}
+
+func formatMinutes(minutes int) string {
+    if minutes < 60 {
+        return fmt.Sprintf("%dm", minutes)
+    }
+    return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
+
+func (r report) title() string {
+    if r.from.Equal(r.to) {
+        return fmt.Sprintf("Report for %s", r.from.Format("Monday, January 2, 2006"))
+    }
+    return fmt.Sprintf("Report for %s – %s", r.from.Format("Jan 2"), r.to.Format("Jan 2, 2006"))
+}
+
+func (r report) markdown() string {
+    var b strings.Builder
+
+    fmt.Fprintf(&b, "# %s\n\n", r.title())
+    fmt.Fprintf(&b, "- Planned: %s\n", formatMinutes(r.total.planned))
+    fmt.Fprintf(&b, "- Tracked: %s\n", formatMinutes(r.total.tracked))
+    fmt.Fprintf(&b, "- Completed: %d/%d (%d%%)\n", r.total.done, r.total.tasks, r.total.completion())
+    fmt.Fprintf(&b, "- Overruns: %d\n", r.total.overruns)
+
+    sections := []struct {
+        heading string
+        rows    []reportRow
+    }{
+        {"By day", r.days},
+        {"By tag", r.tags},
+    }
+    for _, section := range sections {
+        fmt.Fprintf(&b, "\n## %s\n\n", section.heading)
+        b.WriteString("| | Planned | Tracked | Done | Completion | Overruns |\n")
+        b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+        for _, row := range section.rows {
+            fmt.Fprintf(&b, "| %s | %s | %s | %d/%d | %d%% | %d |\n",
+                row.label,
+                formatMinutes(row.planned),
+                formatMinutes(row.tracked),
+                row.done,
+                row.tasks,
+                row.completion(),
+                row.overruns,
+            )
+        }
+    }
+
+    return b.String()
+}
+
+func (r report) csv() (string, error) {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+
+    records := [][]string{{
+        "group", "key", "planned_minutes", "tracked_minutes", "tasks", "done", "completion_percent", "overruns",
+    }}
+    groups := []struct {
+        name string
+        rows []reportRow
+    }{
+        {"total", []reportRow{r.total}},
+        {"day", r.days},
+        {"tag", r.tags},
+    }
+    for _, group := range groups {
+        for _, row := range group.rows {
+            records = append(records, []string{
+                group.name,
+                row.label,
+                strconv.Itoa(row.planned),
+                strconv.Itoa(row.tracked),
+                strconv.Itoa(row.tasks),
+                strconv.Itoa(row.done),
+                strconv.Itoa(row.completion()),
+                strconv.Itoa(row.overruns),
+            })
+        }
+    }
+
+    if err := w.WriteAll(records); err != nil {
+        return "", err
+    }
+    return buf.String(), nil
+}
+
+func (r report) export(format string) (string, error) {
+    switch format {
+    case "md", "markdown":
+        return r.markdown(), nil
+    case "csv":
+        return r.csv()
+    default:
+        return "", fmt.Errorf("unknown report format %q (want md or csv)", format)
+    }
+}
+
+func loadReport(database *db.DB, date time.Time, week bool) (report, error) {
+    from, to := reportRange(date, week)
+    tasks, err := database.GetTasksBetween(dayStart(from), dayStart(to).AddDate(0, 0, 1))
+    if err != nil {
+        return report{}, err
+    }
+    return buildReport(tasks, from, to), nil
+}
+
+func runReport(args []string) error {
+    fs := flag.NewFlagSet("report", flag.ContinueOnError)
+    week := fs.Bool("week", false, "report on the whole week (Monday to Sunday) containing the date")
+    dateStr := fs.String("date", "", "day to report on as YYYY-MM-DD (default today)")
+    format := fs.String("format", "md", "output format: md or csv")
+    output := fs.String("o", "", "write the report to this file instead of stdout")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+
+    date := time.Now().In(s.location)
+    if *dateStr != "" {
+        date, err = time.ParseInLocation("2006-01-02", *dateStr, s.location)
+        if err != nil {
+            return fmt.Errorf("invalid date %q: %v", *dateStr, err)
+        }
+    }
+
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    r, err := loadReport(database, date, *week)
+    if err != nil {
+        return fmt.Errorf("failed to build report: %v", err)
+    }
+    out, err := r.export(*format)
+    if err != nil {
+        return err
+    }
+
+    if *output == "" {
+        fmt.Print(out)
+        return nil
+    }
+    return os.WriteFile(*output, []byte(out), 0644)
+}
+
+func (r report) view() string {
+    var b strings.Builder
+
+    fmt.Fprintf(&b, "%s\n\n", r.title())
+    fmt.Fprintf(&b, "Planned %s • Tracked %s\n", formatMinutes(r.total.planned), formatMinutes(r.total.tracked))
+    fmt.Fprintf(&b, "Done %d/%d (%d%%) • Overruns %d\n",
+        r.total.done, r.total.tasks, r.total.completion(), r.total.overruns)
+
+    row := func(label string, rr reportRow) {
+        fmt.Fprintf(&b, "%-11.11s %7s %7s %5s %4d\n",
+            label,
+            formatMinutes(rr.planned),
+            formatMinutes(rr.tracked),
+            fmt.Sprintf("%d/%d", rr.done, rr.tasks),
+            rr.overruns,
+        )
+    }
+    header := fmt.Sprintf("%-11s %7s %7s %5s %4s\n", "", "Plan", "Track", "Done", "Over")
+
+    if len(r.days) > 1 {
+        b.WriteString("\n" + header)
+        for _, d := range r.days {
+            day, _ := time.Parse("2006-01-02", d.label)
+            row(day.Format("Mon Jan 2"), d)
+        }
+    }
+
+    b.WriteString("\n" + header)
+    for _, t := range r.tags {
+        row(t.label, t)
+    }
+
+    return strings.TrimRight(b.String(), "\n")
+}
+
+func (m *model) openReport() {
+    r, err := loadReport(m.db, m.currentDate, m.reportWeek)
+    if err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to build report: %v", err)
+        m.errorTimer = time.Now()
+        return
+    }
+    m.report = r
+    m.reportNote = ""
+    m.mode = reportMode
+}
+
+func (m *model) exportReport(format string) {
+    out, err := m.report.export(format)
+    if err != nil {
+        m.reportNote = err.Error()
+        return
+    }
+    name := fmt.Sprintf("report-%s.%s", m.report.from.Format("2006-01-02"), format)
+    if !m.report.from.Equal(m.report.to) {
+        name = fmt.Sprintf("report-week-%s.%s", m.report.from.Format("2006-01-02"), format)
+    }
+    if err := os.WriteFile(name, []byte(out), 0644); err != nil {
+        m.reportNote = fmt.Sprintf("Failed to export: %v", err)
+        return
+    }
+    m.reportNote = fmt.Sprintf("Saved %s", name)
+}
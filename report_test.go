@@ -0,0 +1,45 @@
+package main
+
+import (
+    "testing"
+    "time"
+
+    "scheduler/db"
+)
+
+func TestBuildReportWeek(t *testing.T) {
+    from, to := reportRange(time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC), true)
+    tasks := []db.Task{
+        {StartsAt: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), Title: "Standup #work", Duration: 15, Done: true, Tracked: 20 * time.Minute},
+        {StartsAt: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), Title: "Review PRs #work", Duration: 60},
+        {StartsAt: time.Date(2026, 10, 22, 9, 0, 0, 0, time.UTC), Title: "Plan sprint #work", Duration: 30, Done: true, Tracked: 25 * time.Minute},
+        {StartsAt: time.Date(2026, 10, 25, 9, 0, 0, 0, time.UTC), Title: "Long run", Duration: 45},
+    }
+
+    r := buildReport(tasks, from, to)
+    want := []struct {
+        label            string
+        planned, tracked int
+        tasks, done      int
+    }{
+        {"2026-10-19", 75, 20, 2, 1},
+        {"2026-10-20", 0, 0, 0, 0},
+        {"2026-10-21", 0, 0, 0, 0},
+        {"2026-10-22", 30, 25, 1, 1},
+        {"2026-10-23", 0, 0, 0, 0},
+        {"2026-10-24", 0, 0, 0, 0},
+        {"2026-10-25", 45, 0, 1, 0},
+    }
+    if len(r.days) != len(want) {
+        t.Fatalf("got %d days, want %d", len(r.days), len(want))
+    }
+    for i, w := range want {
+        got := r.days[i]
+        if got.label != w.label || got.planned != w.planned || got.tracked != w.tracked || got.tasks != w.tasks || got.done != w.done {
+            t.Errorf("day %d: got %+v, want %+v", i, got, w)
+        }
+    }
+    if r.total.planned != 150 || r.total.tasks != 4 {
+        t.Errorf("total: %+v", r.total)
+    }
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "os/signal"
+    "path/filepath"
+    "sync"
+    "syscall"
+    "time"
+
+    "scheduler/db"
+)
+
+// JSON-RPC 2.0 error codes; -32001 is ours for a missing task or calendar.
+const (
+    rpcParseError     = -32700
+    rpcInvalidRequest = -32600
+    rpcMethodNotFound = -32601
+    rpcInvalidParams  = -32602
+    rpcInternalError  = -32603
+    rpcNotFound       = -32001
+)
+
+type rpcRequest struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id,omitempty"`
+    Method  string          `json:"method"`
+    Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+    Code    int    `json:"code"`
+    Message string `json:"message"`
+}
+
+type rpcResponse struct {
+    JSONRPC string          `json:"jsonrpc"`
+    ID      json.RawMessage `json:"id"`
+    Result  any             `json:"result,omitempty"`
+    Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+    JSONRPC string `json:"jsonrpc"`
+    Method  string `json:"method"`
+    Params  any    `json:"params"`
+}
+
+// rpcClient is one connection. Responses and notifications are written from
+// different goroutines, so writes go through mu.
+type rpcClient struct {
+    conn net.Conn
+    mu   sync.Mutex
+    enc  *json.Encoder
+}
+
+func (c *rpcClient) send(v any) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+    return c.enc.Encode(v)
+}
+
+// rpcServer shares one database connection between all clients. Calls are
+// run one at a time so that read-modify-write methods such as tasks.update
+// cannot interleave.
+type rpcServer struct {
+    *server
+    calls   sync.Mutex
+    mu      sync.Mutex
+    clients map[*rpcClient]struct{}
+    version int64
+}
+
+func defaultSocketPath() (string, error) {
+    if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+        return filepath.Join(dir, "scheduler.sock"), nil
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", fmt.Errorf("failed to get home directory: %v", err)
+    }
+    return filepath.Join(home, ".scheduler", "scheduler.sock"), nil
+}
+
+func runRPC(args []string) error {
+    fs := flag.NewFlagSet("rpc", flag.ContinueOnError)
+    socket := fs.String("socket", "", "Unix socket to listen on (default $XDG_RUNTIME_DIR/scheduler.sock)")
+    poll := fs.Duration("poll", time.Second, "how often to check for changes made by other processes")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    path := *socket
+    if path == "" {
+        var err error
+        if path, err = defaultSocketPath(); err != nil {
+            return err
+        }
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer database.Close()
+
+    ln, err := listenUnix(path)
+    if err != nil {
+        return err
+    }
+    defer os.Remove(path)
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    go func() {
+        <-ctx.Done()
+        ln.Close()
+    }()
+
+    srv := &rpcServer{
+        server:  &server{db: database, settings: s},
+        clients: make(map[*rpcClient]struct{}),
+    }
+    if srv.version, err = database.DataVersion(); err != nil {
+        return err
+    }
+    go srv.watch(ctx, *poll)
+
+    log.Printf("listening for JSON-RPC on %s", path)
+    for {
+        conn, err := ln.Accept()
+        if err != nil {
+            if ctx.Err() != nil {
+                return nil
+            }
+            return err
+        }
+        go srv.serve(conn)
+    }
+}
+
+// listenUnix listens on path, clearing a socket left behind by a process that
+// is no longer running but refusing to take over from one that is.
+func listenUnix(path string) (net.Listener, error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return nil, fmt.Errorf("failed to create socket directory: %v", err)
+    }
+    if conn, err := net.Dial("unix", path); err == nil {
+        conn.Close()
+        return nil, fmt.Errorf("%s is already in use by another scheduler", path)
+    }
+    os.Remove(path)
+
+    ln, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.Chmod(path, 0600); err != nil {
+        ln.Close()
+        return nil, err
+    }
+    return ln, nil
+}
+
+// watch notifies every client when another process, such as the TUI or
+// scheduler serve, commits to the database.
+func (s *rpcServer) watch(ctx context.Context, poll time.Duration) {
+    ticker := time.NewTicker(poll)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        s.calls.Lock()
+        v, err := s.db.DataVersion()
+        changed := err == nil && v != s.version
+        if changed {
+            s.version = v
+        }
+        s.calls.Unlock()
+
+        if err != nil {
+            log.Printf("Failed to check for changes: %v", err)
+        } else if changed {
+            s.broadcast()
+        }
+    }
+}
+
+func (s *rpcServer) broadcast() {
+    s.mu.Lock()
+    clients := make([]*rpcClient, 0, len(s.clients))
+    for c := range s.clients {
+        clients = append(clients, c)
+    }
+    s.mu.Unlock()
+
+    note := rpcNotification{JSONRPC: "2.0", Method: "tasks.changed", Params: struct{}{}}
+    for _, c := range clients {
+        if err := c.send(note); err != nil {
+            c.conn.Close()
+        }
+    }
+}
+
+// serve reads one JSON-RPC request per line until the client disconnects.
+func (s *rpcServer) serve(conn net.Conn) {
+    c := &rpcClient{conn: conn, enc: json.NewEncoder(conn)}
+    s.mu.Lock()
+    s.clients[c] = struct{}{}
+    s.mu.Unlock()
+    defer func() {
+        s.mu.Lock()
+        delete(s.clients, c)
+        s.mu.Unlock()
+        conn.Close()
+    }()
+
+    scanner := bufio.NewScanner(conn)
+    scanner.Buffer(make([]byte, 64*1024), 1<<20)
+    for scanner.Scan() {
+        line := scanner.Bytes()
+        if len(line) == 0 {
+            continue
+        }
+
+        var req rpcRequest
+        if err := json.Unmarshal(line, &req); err != nil {
+            c.send(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+                Error: &rpcError{rpcParseError, fmt.Sprintf("parse error: %v", err)}})
+            continue
+        }
+        if req.JSONRPC != "2.0" || req.Method == "" {
+            c.send(rpcResponse{JSONRPC: "2.0", ID: nullID(req.ID),
+                Error: &rpcError{rpcInvalidRequest, "invalid request"}})
+            continue
+        }
+
+        result, changed, err := s.call(req.Method, req.Params)
+        // Requests without an id are notifications and get no reply. The
+        // caller hears back before anyone is told about the change.
+        if req.ID != nil {
+            resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+            if err != nil {
+                resp.Result = nil
+                resp.Error = toRPCError(err)
+            } else if result == nil {
+                resp.Result = struct{}{}
+            }
+            if err := c.send(resp); err != nil {
+                return
+            }
+        }
+        if changed {
+            s.broadcast()
+        }
+    }
+}
+
+func nullID(id json.RawMessage) json.RawMessage {
+    if id == nil {
+        return json.RawMessage("null")
+    }
+    return id
+}
+
+var errMethodNotFound = errors.New("method not found")
+
+func toRPCError(err error) *rpcError {
+    var apiErr apiError
+    switch {
+    case errors.Is(err, errMethodNotFound):
+        return &rpcError{rpcMethodNotFound, err.Error()}
+    case errors.As(err, &apiErr) && apiErr.status < http.StatusInternalServerError:
+        return &rpcError{rpcInvalidParams, err.Error()}
+    case errors.Is(err, db.ErrTaskNotFound), errors.Is(err, db.ErrCalendarNotFound):
+        return &rpcError{rpcNotFound, err.Error()}
+    }
+    log.Printf("rpc: %v", err)
+    return &rpcError{rpcInternalError, "internal error"}
+}
+
+type rpcParams struct {
+    taskInput
+    ID   int64   `json:"id"`
+    Date *string `json:"date"`
+    From *string `json:"from"`
+    To   *string `json:"to"`
+    Text string  `json:"text"`
+}
+
+// call runs one method and reports whether it changed the schedule.
+func (s *rpcServer) call(method string, raw json.RawMessage) (any, bool, error) {
+    var p rpcParams
+    if len(raw) > 0 {
+        if err := json.Unmarshal(raw, &p); err != nil {
+            return nil, false, badRequest("invalid params: %v", err)
+        }
+    }
+
+    s.calls.Lock()
+    defer s.calls.Unlock()
+
+    var result any
+    var err error
+    switch method {
+    case "tasks.list":
+        result, err = s.listTasks(p.Date, p.From, p.To)
+        return result, false, err
+    case "tasks.get":
+        result, err = s.getTask(p.ID)
+        return result, false, err
+    case "calendars.list":
+        result, err = s.listCalendars()
+        return result, false, err
+    case "tasks.create":
+        result, err = s.createTask(p.taskInput)
+    case "tasks.add":
+        result, err = s.quickAdd(p.Text, p.taskInput)
+    case "tasks.update":
+        result, err = s.updateTask(p.ID, p.taskInput)
+    case "tasks.complete":
+        done := true
+        if p.Done != nil {
+            done = *p.Done
+        }
+        result, err = s.setDone(p.ID, done)
+    case "tasks.delete":
+        err = s.db.DeleteTask(p.ID)
+    default:
+        return nil, false, fmt.Errorf("%w: %s", errMethodNotFound, method)
+    }
+    if err != nil {
+        return nil, false, err
+    }
+
+    // Our own writes do not move data_version, so remember where it is to
+    // avoid announcing the same change twice.
+    if v, err := s.db.DataVersion(); err == nil {
+        s.version = v
+    }
+    return result, true, nil
+}
+
+// quickAdd creates a task from a quick-add line such as "review PR tomorrow
+// 10am 45m". Without a time it is placed in the current slot.
+func (s *rpcServer) quickAdd(text string, in taskInput) (apiTask, error) {
+    now := time.Now().In(s.settings.location)
+    minute := now.Hour()*60 + now.Minute()
+    slot := slotStartTime(now, minute-minute%s.settings.slotMinutes)
+    task, err := parseQuickAdd(text, slot, now, s.settings)
+    if err != nil {
+        return apiTask{}, invalid(err)
+    }
+    in.Title, in.StartsAt, in.Duration = &task.title, &task.startsAt, &task.duration
+    return s.createTask(in)
+}
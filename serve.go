@@ -0,0 +1,411 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+
+    "scheduler/db"
+)
+
+// apiTask is how tasks are written to and read from the HTTP API.
+type apiTask struct {
+    ID             int64     `json:"id"`
+    CalendarID     int64     `json:"calendar_id"`
+    Title          string    `json:"title"`
+    StartsAt       time.Time `json:"starts_at"`
+    TimeZone       string    `json:"time_zone"`
+    Duration       int       `json:"duration"`
+    Done           bool      `json:"done"`
+    Tags           []string  `json:"tags"`
+    Pomodoros      int       `json:"pomodoros"`
+    TrackedSeconds int64     `json:"tracked_seconds"`
+}
+
+// taskInput is the body of a create or update request. Fields left out of
+// an update keep their current value.
+type taskInput struct {
+    CalendarID *int64     `json:"calendar_id"`
+    Title      *string    `json:"title"`
+    StartsAt   *time.Time `json:"starts_at"`
+    TimeZone   *string    `json:"time_zone"`
+    Duration   *int       `json:"duration"`
+    Done       *bool      `json:"done"`
+}
+
+type apiCalendar struct {
+    ID     int64  `json:"id"`
+    Name   string `json:"name"`
+    Color  string `json:"color"`
+    Hidden bool   `json:"hidden"`
+}
+
+type server struct {
+    db       *db.DB
+    settings settings
+}
+
+// apiError carries the status code an error should be answered with.
+type apiError struct {
+    status int
+    msg    string
+}
+
+func (e apiError) Error() string { return e.msg }
+
+func badRequest(format string, args ...any) error {
+    return apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func invalid(err error) error {
+    return apiError{http.StatusUnprocessableEntity, err.Error()}
+}
+
+func toAPITask(t db.Task) apiTask {
+    tags := t.Tags()
+    if tags == nil {
+        tags = []string{}
+    }
+    return apiTask{
+        ID:             t.ID,
+        CalendarID:     t.CalendarID,
+        Title:          t.Title,
+        StartsAt:       t.StartsAt,
+        TimeZone:       t.TZ,
+        Duration:       t.Duration,
+        Done:           t.Done,
+        Tags:           tags,
+        Pomodoros:      t.Pomodoros,
+        TrackedSeconds: int64(t.Tracked.Seconds()),
+    }
+}
+
+func runServe(args []string) error {
+    fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+    addr := fs.String("addr", "127.0.0.1:7420", "address to listen on")
+    if err := fs.Parse(args); err != nil {
+        return err
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    database, err := db.Open(s.dbPath)
+    if err != nil {
+        return err
+    }
+    defer database.Close()
+
+    srv := &server{db: database, settings: s}
+    // Timeouts so a client that stops sending cannot hold a connection open.
+    httpServer := &http.Server{
+        Addr:              *addr,
+        Handler:           srv.routes(),
+        ReadHeaderTimeout: 5 * time.Second,
+        ReadTimeout:       30 * time.Second,
+        IdleTimeout:       2 * time.Minute,
+    }
+    log.Printf("serving the schedule API on http://%s", *addr)
+    return httpServer.ListenAndServe()
+}
+
+func (s *server) routes() http.Handler {
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /tasks", s.handle(s.httpList))
+    mux.HandleFunc("POST /tasks", s.handle(s.httpCreate))
+    mux.HandleFunc("GET /tasks/{id}", s.handle(s.httpGet))
+    mux.HandleFunc("PATCH /tasks/{id}", s.handle(s.httpUpdate))
+    mux.HandleFunc("DELETE /tasks/{id}", s.handle(s.httpDelete))
+    mux.HandleFunc("PUT /tasks/{id}/done", s.handle(s.httpDone(true)))
+    mux.HandleFunc("DELETE /tasks/{id}/done", s.handle(s.httpDone(false)))
+    mux.HandleFunc("GET /calendars", s.handle(s.httpCalendars))
+    return mux
+}
+
+// handle turns a handler's result into a JSON response: the value with the
+// returned status, or {"error": ...} with the status the error asks for.
+func (s *server) handle(h func(*http.Request) (int, any, error)) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        status, body, err := h(r)
+        if err != nil {
+            var apiErr apiError
+            switch {
+            case errors.As(err, &apiErr):
+                status = apiErr.status
+            case errors.Is(err, db.ErrTaskNotFound), errors.Is(err, db.ErrCalendarNotFound):
+                status = http.StatusNotFound
+            default:
+                log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+                status = http.StatusInternalServerError
+                err = errors.New("internal error")
+            }
+            body = map[string]string{"error": err.Error()}
+        }
+
+        if status == http.StatusNoContent {
+            w.WriteHeader(status)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(status)
+        json.NewEncoder(w).Encode(body)
+    }
+}
+
+func (s *server) parseDay(name, value string) (time.Time, error) {
+    date, err := time.ParseInLocation("2006-01-02", value, s.settings.location)
+    if err != nil {
+        return date, badRequest("%s: want a date like 2006-01-02, got %q", name, value)
+    }
+    return date, nil
+}
+
+// listTasks returns the tasks on date, or on the days from to to inclusive,
+// in the configured timezone. With none of them it lists today. A nil
+// argument was not given at all; an empty one is checked like any other.
+func (s *server) listTasks(date, from, to *string) ([]apiTask, error) {
+    first := dayStart(time.Now().In(s.settings.location))
+    last := first
+
+    var err error
+    switch {
+    case date != nil:
+        if from != nil || to != nil {
+            return nil, badRequest("use either date or from/to")
+        }
+        if first, err = s.parseDay("date", *date); err != nil {
+            return nil, err
+        }
+        last = first
+    case from != nil || to != nil:
+        if from == nil || to == nil {
+            return nil, badRequest("from and to must be given together")
+        }
+        if first, err = s.parseDay("from", *from); err != nil {
+            return nil, err
+        }
+        if last, err = s.parseDay("to", *to); err != nil {
+            return nil, err
+        }
+        if last.Before(first) {
+            return nil, badRequest("to is before from")
+        }
+    }
+
+    tasks, err := s.db.GetTasksBetween(first, last.AddDate(0, 0, 1))
+    if err != nil {
+        return nil, err
+    }
+    out := make([]apiTask, 0, len(tasks))
+    for _, t := range tasks {
+        out = append(out, toAPITask(t))
+    }
+    return out, nil
+}
+
+func (s *server) getTask(id int64) (apiTask, error) {
+    task, err := s.db.GetTask(id)
+    if err != nil {
+        return apiTask{}, err
+    }
+    return toAPITask(task), nil
+}
+
+// apply copies the given fields onto task and checks the result with the
+// same rules as the task form.
+func (s *server) apply(in taskInput, task *db.Task) error {
+    if in.CalendarID != nil {
+        task.CalendarID = *in.CalendarID
+    }
+    if in.Title != nil {
+        task.Title = *in.Title
+    }
+    if in.Duration != nil {
+        task.Duration = *in.Duration
+    }
+    if in.Done != nil {
+        task.Done = *in.Done
+    }
+
+    loc := task.StartsAt.Location()
+    if in.TimeZone != nil {
+        var err error
+        if loc, err = time.LoadLocation(*in.TimeZone); err != nil || *in.TimeZone == "" {
+            return invalid(fmt.Errorf("unknown time_zone %q", *in.TimeZone))
+        }
+    }
+    if in.StartsAt != nil {
+        task.StartsAt = *in.StartsAt
+    }
+    task.StartsAt = task.StartsAt.In(loc).Truncate(time.Minute)
+
+    if err := validateTask(task.Title, task.Duration, s.settings); err != nil {
+        return invalid(err)
+    }
+    calendars, err := s.db.Calendars()
+    if err != nil {
+        return err
+    }
+    for _, c := range calendars {
+        if c.ID == task.CalendarID {
+            return nil
+        }
+    }
+    return invalid(fmt.Errorf("calendar %d does not exist", task.CalendarID))
+}
+
+func (s *server) createTask(in taskInput) (apiTask, error) {
+    if in.StartsAt == nil {
+        return apiTask{}, invalid(errors.New("starts_at is required"))
+    }
+    if in.Title == nil {
+        return apiTask{}, invalid(errors.New("title is required"))
+    }
+
+    task := db.Task{
+        CalendarID: 1,
+        StartsAt:   in.StartsAt.In(s.settings.location),
+        Duration:   s.settings.defaultDuration,
+    }
+    if err := s.apply(in, &task); err != nil {
+        return apiTask{}, err
+    }
+
+    id, err := s.db.CreateTask(task)
+    if err != nil {
+        return apiTask{}, err
+    }
+    return s.getTask(id)
+}
+
+func (s *server) updateTask(id int64, in taskInput) (apiTask, error) {
+    task, err := s.db.GetTask(id)
+    if err != nil {
+        return apiTask{}, err
+    }
+    if err := s.apply(in, &task); err != nil {
+        return apiTask{}, err
+    }
+    if err := s.db.UpdateTask(task); err != nil {
+        return apiTask{}, err
+    }
+    return s.getTask(id)
+}
+
+func (s *server) setDone(id int64, done bool) (apiTask, error) {
+    if _, err := s.db.GetTask(id); err != nil {
+        return apiTask{}, err
+    }
+    if err := s.db.UpdateTaskDone(id, done); err != nil {
+        return apiTask{}, err
+    }
+    return s.getTask(id)
+}
+
+func (s *server) listCalendars() ([]apiCalendar, error) {
+    calendars, err := s.db.Calendars()
+    if err != nil {
+        return nil, err
+    }
+    out := make([]apiCalendar, 0, len(calendars))
+    for _, c := range calendars {
+        out = append(out, apiCalendar{ID: c.ID, Name: c.Name, Color: c.Color, Hidden: c.Hidden})
+    }
+    return out, nil
+}
+
+func pathID(r *http.Request) (int64, error) {
+    id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+    if err != nil {
+        return 0, badRequest("invalid task id %q", r.PathValue("id"))
+    }
+    return id, nil
+}
+
+func decodeInput(r *http.Request) (taskInput, error) {
+    var in taskInput
+    dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&in); err != nil {
+        return in, badRequest("invalid JSON body: %v", err)
+    }
+    return in, nil
+}
+
+func (s *server) httpList(r *http.Request) (int, any, error) {
+    q := r.URL.Query()
+    tasks, err := s.listTasks(queryParam(q, "date"), queryParam(q, "from"), queryParam(q, "to"))
+    return http.StatusOK, tasks, err
+}
+
+// queryParam returns name from q, or nil when q does not have it.
+func queryParam(q url.Values, name string) *string {
+    if !q.Has(name) {
+        return nil
+    }
+    v := q.Get(name)
+    return &v
+}
+
+func (s *server) httpGet(r *http.Request) (int, any, error) {
+    id, err := pathID(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    task, err := s.getTask(id)
+    return http.StatusOK, task, err
+}
+
+func (s *server) httpCreate(r *http.Request) (int, any, error) {
+    in, err := decodeInput(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    task, err := s.createTask(in)
+    return http.StatusCreated, task, err
+}
+
+func (s *server) httpUpdate(r *http.Request) (int, any, error) {
+    id, err := pathID(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    in, err := decodeInput(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    task, err := s.updateTask(id, in)
+    return http.StatusOK, task, err
+}
+
+func (s *server) httpDone(done bool) func(*http.Request) (int, any, error) {
+    return func(r *http.Request) (int, any, error) {
+        id, err := pathID(r)
+        if err != nil {
+            return 0, nil, err
+        }
+        task, err := s.setDone(id, done)
+        return http.StatusOK, task, err
+    }
+}
+
+func (s *server) httpDelete(r *http.Request) (int, any, error) {
+    id, err := pathID(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    return http.StatusNoContent, nil, s.db.DeleteTask(id)
+}
+
+func (s *server) httpCalendars(r *http.Request) (int, any, error) {
+    calendars, err := s.listCalendars()
+    return http.StatusOK, calendars, err
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestCreateTaskDone(t *testing.T) {
+    database := openTestDB(t)
+    srv := &server{db: database, settings: testSettings(t, time.UTC)}
+    title, done := "Paid rent", true
+    startsAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+
+    task, err := srv.createTask(taskInput{Title: &title, StartsAt: &startsAt, Done: &done})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !task.Done {
+        t.Errorf("created task is not done")
+    }
+    // Done is written with the task, not as a later edit.
+    history, err := database.TaskHistory(task.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(history) != 1 {
+        t.Errorf("got %d history entries, want only the creation: %+v", len(history), history)
+    }
+}
+
+func TestListTasksQuery(t *testing.T) {
+    srv := &server{db: openTestDB(t), settings: testSettings(t, time.UTC)}
+    tests := []struct {
+        query  string
+        status int
+    }{
+        {"", http.StatusOK},
+        {"?date=2026-10-19", http.StatusOK},
+        {"?from=2026-10-19&to=2026-10-25", http.StatusOK},
+        // Given but empty is a bad date, not a request for today.
+        {"?date=", http.StatusBadRequest},
+        {"?date=&from=2026-10-19", http.StatusBadRequest},
+        {"?from=2026-10-19&to=", http.StatusBadRequest},
+    }
+    for _, tt := range tests {
+        w := httptest.NewRecorder()
+        srv.routes().ServeHTTP(w, httptest.NewRequest("GET", "/tasks"+tt.query, nil))
+        if w.Code != tt.status {
+            t.Errorf("GET /tasks%s: status %d, want %d: %s", tt.query, w.Code, tt.status, w.Body)
+        }
+    }
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+
+    "scheduler/db"
+)
+
+// runSync merges the change logs of this machine's database and another one
+// in both directions, leaving the two with the same tasks. The other side may
+// be a database file or a directory, such as a shared folder, holding a
+// scheduler.db; one is created there on first sync.
+func runSync(args []string) error {
+    if len(args) != 1 {
+        return errors.New("usage: scheduler sync <other.db or directory>")
+    }
+
+    s, err := loadSettings()
+    if err != nil {
+        return err
+    }
+    other := expandHome(args[0])
+    if info, err := os.Stat(other); err == nil && info.IsDir() {
+        other = filepath.Join(other, "scheduler.db")
+    }
+    if a, err := os.Stat(s.dbPath); err == nil {
+        if b, err := os.Stat(other); err == nil && os.SameFile(a, b) {
+            return fmt.Errorf("%s is this machine's own database", other)
+        }
+    }
+
+    local, err := db.Open(s.dbPath)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %v", err)
+    }
+    defer local.Close()
+    remote, err := db.Open(other)
+    if err != nil {
+        return fmt.Errorf("%s: %v", other, err)
+    }
+    defer remote.Close()
+
+    // A database copied from another machine starts out with its device id;
+    // changes made from here on should be told apart.
+    localDevice, err := local.Device()
+    if err != nil {
+        return err
+    }
+    remoteDevice, err := remote.Device()
+    if err != nil {
+        return err
+    }
+    if localDevice == remoteDevice {
+        if localDevice, err = local.ResetDevice(); err != nil {
+            return err
+        }
+        fmt.Printf("%s was copied from this database; this machine is now device %s\n", other, localDevice)
+    }
+
+    incoming, err := local.MissingChanges(remote)
+    if err != nil {
+        return err
+    }
+    received, err := local.ApplyChanges(incoming)
+    if err != nil {
+        return fmt.Errorf("failed to merge %s: %v", other, err)
+    }
+
+    outgoing, err := remote.MissingChanges(local)
+    if err != nil {
+        return err
+    }
+    sent, err := remote.ApplyChanges(outgoing)
+    if err != nil {
+        return fmt.Errorf("failed to merge into %s: %v", other, err)
+    }
+
+    fmt.Printf("received %d changes (%d tasks updated), sent %d changes (%d tasks updated)\n",
+        len(incoming), received, len(outgoing), sent)
+    return nil
+}
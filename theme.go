@@ -0,0 +1,174 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io/fs"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/BurntSushi/toml"
+)
+
+type colorConfig struct {
+    Border            string `toml:"border,omitempty"`
+    Header            string `toml:"header,omitempty"`
+    SlotBorder        string `toml:"slot_border,omitempty"`
+    SelectedBorder    string `toml:"selected_border,omitempty"`
+    SelectedText      string `toml:"selected_text,omitempty"`
+    CurrentBorder     string `toml:"current_border,omitempty"`
+    CurrentBackground string `toml:"current_background,omitempty"`
+    Task              string `toml:"task,omitempty"`
+    SelectedTask      string `toml:"selected_task,omitempty"`
+    FormBorder        string `toml:"form_border,omitempty"`
+    Error             string `toml:"error,omitempty"`
+}
+
+// themeFile is the layout of a user theme in the themes directory. Colors it
+// leaves out come from its base theme.
+type themeFile struct {
+    Base string `toml:"base"`
+    colorConfig
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+var builtinThemes = map[string]colorConfig{
+    "dark": {
+        Border:            "39",
+        Header:            "219",
+        SlotBorder:        "241",
+        SelectedBorder:    "218",
+        SelectedText:      "255",
+        CurrentBorder:     "39",
+        CurrentBackground: "52",
+        Task:              "86",
+        SelectedTask:      "86",
+        FormBorder:        "63",
+        Error:             "196",
+    },
+    "light": {
+        Border:            "25",
+        Header:            "127",
+        SlotBorder:        "245",
+        SelectedBorder:    "161",
+        SelectedText:      "232",
+        CurrentBorder:     "25",
+        CurrentBackground: "224",
+        Task:              "28",
+        SelectedTask:      "22",
+        FormBorder:        "61",
+        Error:             "160",
+    },
+    "high-contrast": {
+        Border:            "15",
+        Header:            "11",
+        SlotBorder:        "15",
+        SelectedBorder:    "11",
+        SelectedText:      "15",
+        CurrentBorder:     "14",
+        CurrentBackground: "4",
+        Task:              "15",
+        SelectedTask:      "11",
+        FormBorder:        "15",
+        Error:             "9",
+    },
+}
+
+func (c *colorConfig) fields() map[string]*string {
+    return map[string]*string{
+        "border":             &c.Border,
+        "header":             &c.Header,
+        "slot_border":        &c.SlotBorder,
+        "selected_border":    &c.SelectedBorder,
+        "selected_text":      &c.SelectedText,
+        "current_border":     &c.CurrentBorder,
+        "current_background": &c.CurrentBackground,
+        "task":               &c.Task,
+        "selected_task":      &c.SelectedTask,
+        "form_border":        &c.FormBorder,
+        "error":              &c.Error,
+    }
+}
+
+// merge returns c with every color that is set in over replacing its own.
+func (c colorConfig) merge(over colorConfig) colorConfig {
+    fields := c.fields()
+    for name, value := range over.fields() {
+        if *value != "" {
+            *fields[name] = *value
+        }
+    }
+    return c
+}
+
+func (c colorConfig) validate() error {
+    fields := c.fields()
+    names := make([]string, 0, len(fields))
+    for name := range fields {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        if !colorPattern.MatchString(*fields[name]) {
+            return fmt.Errorf("%s: %q is not an ANSI color number or #hex color", name, *fields[name])
+        }
+    }
+    return nil
+}
+
+// loadTheme resolves a theme name for a dark or light terminal background.
+// "auto" picks the dark or light theme to match; other names are built-in
+// themes or files named <name>.toml in themesDir.
+func loadTheme(name, themesDir string, dark bool) (colorConfig, error) {
+    return loadThemeDepth(name, themesDir, dark, 0)
+}
+
+func loadThemeDepth(name, themesDir string, dark bool, depth int) (colorConfig, error) {
+    if name == "auto" {
+        if dark {
+            return builtinThemes["dark"], nil
+        }
+        return builtinThemes["light"], nil
+    }
+    if theme, ok := builtinThemes[name]; ok {
+        return theme, nil
+    }
+    if depth > 8 {
+        return colorConfig{}, fmt.Errorf("theme %q: too many nested base themes", name)
+    }
+
+    if name == "" || strings.ContainsAny(name, `/\`) {
+        return colorConfig{}, fmt.Errorf("unknown theme %q", name)
+    }
+    path := filepath.Join(themesDir, name+".toml")
+
+    tf := themeFile{Base: "dark"}
+    md, err := toml.DecodeFile(path, &tf)
+    if errors.Is(err, fs.ErrNotExist) {
+        return colorConfig{}, fmt.Errorf("unknown theme %q (no built-in theme and no %s)", name, path)
+    }
+    if err != nil {
+        var perr toml.ParseError
+        if errors.As(err, &perr) {
+            return colorConfig{}, fmt.Errorf("%s: %s", path, perr.ErrorWithPosition())
+        }
+        return colorConfig{}, fmt.Errorf("%s: %v", path, err)
+    }
+    if undecoded := md.Undecoded(); len(undecoded) > 0 {
+        return colorConfig{}, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+    }
+
+    base, err := loadThemeDepth(tf.Base, themesDir, dark, depth+1)
+    if err != nil {
+        return colorConfig{}, fmt.Errorf("%s: base: %v", path, err)
+    }
+    theme := base.merge(tf.colorConfig)
+    if err := theme.validate(); err != nil {
+        return colorConfig{}, fmt.Errorf("%s: %v", path, err)
+    }
+    return theme, nil
+}
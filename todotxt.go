@@ -0,0 +1,198 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "scheduler/db"
+)
+
+// todo.txt has no fields for a time of day or a length, so those go in the
+// key:value extensions t: and dur: next to the standard due:.
+var (
+    todoDatePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+    todoPriorityPattern = regexp.MustCompile(`^\([A-Z]\)$`)
+)
+
+// todoItem is a todo.txt line. Missing values are "" or -1 so an update
+// only touches what the line gives.
+type todoItem struct {
+    line     int
+    title    string
+    done     bool
+    date     string
+    clock    int
+    duration int
+    id       int64
+}
+
+func todoLine(t db.Task, loc *time.Location) string {
+    prefix := ""
+    if t.Done {
+        prefix = "x "
+    }
+    startsAt := t.StartsAt.In(loc)
+    return fmt.Sprintf("%s%s due:%s t:%s dur:%d scheduler:%d",
+        prefix, t.Title, startsAt.Format("2006-01-02"), startsAt.Format("15:04"), t.Duration, t.ID)
+}
+
+func exportTodoTxt(tasks []db.Task, loc *time.Location) string {
+    var b strings.Builder
+    for _, t := range tasks {
+        b.WriteString(todoLine(t, loc))
+        b.WriteString("\n")
+    }
+    return b.String()
+}
+
+// parseTodoLine reads one line. A priority is kept in the title so it is
+// written back on export; completion and creation dates are dropped.
+func parseTodoLine(i int, line string) (todoItem, error) {
+    item := todoItem{line: i, clock: -1, duration: -1}
+    words := strings.Fields(line)
+    if len(words) > 0 && words[0] == "x" {
+        item.done = true
+        words = words[1:]
+    }
+    var title []string
+    if len(words) > 0 && todoPriorityPattern.MatchString(words[0]) {
+        title = append(title, words[0])
+        words = words[1:]
+    }
+    for n := 0; n < 2 && len(words) > 0 && todoDatePattern.MatchString(words[0]); n++ {
+        words = words[1:]
+    }
+
+    for _, word := range words {
+        key, value, ok := strings.Cut(word, ":")
+        if !ok || value == "" {
+            title = append(title, word)
+            continue
+        }
+        switch key {
+        case "due":
+            if _, err := time.Parse("2006-01-02", value); err != nil {
+                return item, fmt.Errorf("due: %q is not a YYYY-MM-DD date", value)
+            }
+            item.date = value
+        case "t":
+            clock, ok := parseClockWord(value, true)
+            if !ok {
+                return item, fmt.Errorf("t: %q is not a time of day", value)
+            }
+            item.clock = clock
+        case "dur":
+            duration, err := strconv.Atoi(value)
+            if err != nil {
+                var ok bool
+                if duration, ok = parseDurationWord(value); !ok {
+                    return item, fmt.Errorf("dur: %q is not a duration", value)
+                }
+            }
+            item.duration = duration
+        case "scheduler":
+            id, err := strconv.ParseInt(value, 10, 64)
+            if err != nil {
+                return item, fmt.Errorf("scheduler: %q is not a task ID", value)
+            }
+            item.id = id
+        default:
+            title = append(title, word)
+        }
+    }
+    item.title = strings.Join(title, " ")
+    return item, nil
+}
+
+func (item todoItem) taskID() int64 {
+    return item.id
+}
+
+func (item todoItem) where() string {
+    return fmt.Sprintf("line %d", item.line+1)
+}
+
+// task turns the item into the task it describes, on top of t.
+func (item todoItem) task(t db.Task, s settings) (db.Task, error) {
+    loc := s.location
+    if t.ID != 0 {
+        loc = t.StartsAt.Location()
+    }
+    start := t.StartsAt.In(s.location)
+    date := start.Format("2006-01-02")
+    if item.date != "" {
+        date = item.date
+    }
+    clock := start.Hour()*60 + start.Minute()
+    if item.clock >= 0 {
+        clock = item.clock
+    }
+    day, err := time.ParseInLocation("2006-01-02", date, s.location)
+    if err != nil {
+        return t, err
+    }
+    t.StartsAt = time.Date(day.Year(), day.Month(), day.Day(), clock/60, clock%60, 0, 0, s.location).In(loc)
+
+    t.Title = item.title
+    t.Done = item.done
+    switch {
+    case item.duration >= 0:
+        t.Duration = item.duration
+    case t.ID == 0:
+        t.Duration = s.defaultDuration
+    }
+    return t, validateTask(t.Title, t.Duration, s)
+}
+
+// importTodoTxt applies the lines of a todo.txt file to the database. New
+// lines need a due: date and a t: time to become tasks; save gets lines with
+// a scheduler: ID added to them so importing again updates them instead.
+func importTodoTxt(database *db.DB, lines []string, calendar int64, s settings, save func(out []string) error) (importResult, error) {
+    var result importResult
+    var items []todoItem
+    var entries []fileEntry
+    for i, line := range lines {
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        item, err := parseTodoLine(i, line)
+        if err != nil {
+            return result, fmt.Errorf("%s: %v", item.where(), err)
+        }
+        if item.id == 0 && (item.date == "" || item.clock < 0) {
+            // Not scheduled, so it stays a plain todo.
+            result.skipped++
+            continue
+        }
+        items = append(items, item)
+        entries = append(entries, item)
+    }
+
+    err := applyImport(database, entries, calendar, s, &result, func(ids []int64) error {
+        out := append([]string(nil), lines...)
+        for i, item := range items {
+            if ids[i] != 0 {
+                out[item.line] = strings.TrimRight(out[item.line], " \t") + fmt.Sprintf(" scheduler:%d", ids[i])
+            }
+        }
+        if save == nil {
+            return nil
+        }
+        return save(out)
+    })
+    return result, err
+}
+
+var todoTxtFormat = fileFormat{
+    name:    "todotxt",
+    entries: "lines",
+    export:  exportTodoTxt,
+    apply:   importTodoTxt,
+}
+
+func runTodoTxt(args []string) error {
+    return todoTxtFormat.run(args)
+}
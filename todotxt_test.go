@@ -0,0 +1,99 @@
+package main
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseTodoLine(t *testing.T) {
+    tests := []struct {
+        line string
+        want todoItem
+    }{
+        {
+            "(A) 2026-10-01 Call plumber +house @phone due:2026-10-19 t:9:30 dur:45m",
+            todoItem{title: "(A) Call plumber +house @phone", date: "2026-10-19", clock: 9*60 + 30, duration: 45},
+        },
+        {
+            "x 2026-10-17 2026-10-10 Write report due:2026-10-19 t:14:00 dur:90 scheduler:7",
+            todoItem{title: "Write report", done: true, date: "2026-10-19", clock: 14 * 60, duration: 90, id: 7},
+        },
+        {
+            "Read https://example.com/a:b later",
+            todoItem{title: "Read https://example.com/a:b later", clock: -1, duration: -1},
+        },
+    }
+    for _, tt := range tests {
+        got, err := parseTodoLine(0, tt.line)
+        if err != nil {
+            t.Errorf("%q: %v", tt.line, err)
+            continue
+        }
+        if got != tt.want {
+            t.Errorf("%q:\n got %+v\nwant %+v", tt.line, got, tt.want)
+        }
+    }
+
+    for _, line := range []string{"a due:2026-13-01", "a t:25:00", "a dur:soon", "a scheduler:x"} {
+        if _, err := parseTodoLine(0, line); err == nil {
+            t.Errorf("%q: want an error", line)
+        }
+    }
+}
+
+func TestTodoTxtDST(t *testing.T) {
+    database := openTestDB(t)
+    s := testSettings(t, newYork(t))
+
+    // 9:00 is EDT on the day clocks go forward and EST on the day they go
+    // back; neither is midnight plus nine hours.
+    lines := []string{
+        "Spring standup due:2026-03-08 t:09:00 dur:15",
+        "Fall standup due:2026-11-01 t:09:00 dur:15",
+    }
+    var out []string
+    result, err := importTodoTxt(database, lines, 1, s, func(lines []string) error {
+        out = lines
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result.created != 2 {
+        t.Fatalf("import: %+v", result)
+    }
+
+    wants := []time.Time{
+        time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC),
+        time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+    }
+    for i, want := range wants {
+        item, err := parseTodoLine(i, out[i])
+        if err != nil {
+            t.Fatal(err)
+        }
+        task, err := database.GetTask(item.id)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !task.StartsAt.Equal(want) {
+            t.Errorf("%q starts at %v, want %v", lines[i], task.StartsAt.UTC(), want)
+        }
+    }
+
+    // An export read straight back in is not an edit.
+    for _, day := range []string{"2026-03-08", "2026-11-01"} {
+        date, _ := time.ParseInLocation("2006-01-02", day, s.location)
+        tasks, err := database.GetTasksForDate(date)
+        if err != nil {
+            t.Fatal(err)
+        }
+        exported := strings.Split(strings.TrimSuffix(exportTodoTxt(tasks, s.location), "\n"), "\n")
+        if result, err := importTodoTxt(database, exported, 1, s, nil); err != nil {
+            t.Fatal(err)
+        } else if result != (importResult{unchanged: 1}) {
+            t.Errorf("%s: re-import of %q: %+v", day, exported, result)
+        }
+    }
+}
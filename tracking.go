@@ -0,0 +1,86 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "scheduler/db"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+type trackTickMsg struct {
+    entry int64
+}
+
+func trackTick(entry int64) tea.Cmd {
+    return tea.Tick(time.Second, func(time.Time) tea.Msg {
+        return trackTickMsg{entry: entry}
+    })
+}
+
+func (m *model) loadRunningTimer() error {
+    running, err := m.db.RunningTimer()
+    if err != nil {
+        return err
+    }
+    m.running = running
+    return nil
+}
+
+func (m *model) startTracking(task Task) tea.Cmd {
+    if err := m.db.StartTimer(task.ID, time.Now()); err != nil {
+        if errors.Is(err, db.ErrTimerRunning) {
+            m.errorMsg = fmt.Sprintf("Stop the running timer first (%v)", err)
+        } else {
+            m.errorMsg = fmt.Sprintf("Failed to start timer: %v", err)
+        }
+        m.errorTimer = time.Now()
+        return nil
+    }
+    if err := m.loadRunningTimer(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load timer: %v", err)
+        m.errorTimer = time.Now()
+        return nil
+    }
+    if m.running == nil {
+        return nil
+    }
+    return trackTick(m.running.ID)
+}
+
+func (m *model) stopTracking() {
+    if m.running == nil {
+        return
+    }
+    if err := m.db.StopTimer(time.Now()); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to stop timer: %v", err)
+        m.errorTimer = time.Now()
+        return
+    }
+    m.running = nil
+    if err := m.loadTasks(); err != nil {
+        m.errorMsg = fmt.Sprintf("Failed to load tasks: %v", err)
+        m.errorTimer = time.Now()
+    }
+}
+
+func (m model) updateTracking(msg trackTickMsg) tea.Cmd {
+    if m.running == nil || m.running.ID != msg.entry {
+        return nil
+    }
+    return trackTick(msg.entry)
+}
+
+func formatTracked(d time.Duration) string {
+    d = d.Round(time.Second)
+    return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func (m model) runningTimerView() string {
+    if m.running == nil {
+        return ""
+    }
+    return fmt.Sprintf("⏱ %s %s", m.running.TaskTitle, formatTracked(time.Since(m.running.StartedAt)))
+}
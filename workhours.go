@@ -0,0 +1,137 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// dayWindow is the visible part of a day in minutes past midnight. Slots
+// outside it are collapsed into a single expandable row.
+type dayWindow struct {
+    start int
+    end   int
+}
+
+var fullDay = dayWindow{start: 0, end: 24 * 60}
+
+var weekdayNames = map[string]time.Weekday{
+    "sun": time.Sunday,
+    "mon": time.Monday,
+    "tue": time.Tuesday,
+    "wed": time.Wednesday,
+    "thu": time.Thursday,
+    "fri": time.Friday,
+    "sat": time.Saturday,
+}
+
+func (w dayWindow) isFullDay() bool {
+    return w.start <= 0 && w.end >= 24*60
+}
+
+// snap widens the window to whole slots so no slot straddles its edges.
+func (w dayWindow) snap(slotMinutes int) dayWindow {
+    w.start -= w.start % slotMinutes
+    if rem := w.end % slotMinutes; rem != 0 {
+        w.end += slotMinutes - rem
+    }
+    return w
+}
+
+func parseClock(s string) (int, error) {
+    var h, m int
+    if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil {
+        return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+    }
+    if h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+        return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+    }
+    return h*60 + m, nil
+}
+
+func parseWindow(s string) (dayWindow, error) {
+    if s == "off" {
+        return dayWindow{}, nil
+    }
+    start, end, ok := strings.Cut(s, "-")
+    if !ok {
+        return dayWindow{}, fmt.Errorf("invalid hours %q, want HH:MM-HH:MM or off", s)
+    }
+    var w dayWindow
+    var err error
+    if w.start, err = parseClock(start); err != nil {
+        return dayWindow{}, err
+    }
+    if w.end, err = parseClock(end); err != nil {
+        return dayWindow{}, err
+    }
+    if w.end <= w.start {
+        return dayWindow{}, fmt.Errorf("invalid hours %q, end must be after start", s)
+    }
+    return w, nil
+}
+
+func parseWeekdays(s string) ([]time.Weekday, error) {
+    from, to, isRange := strings.Cut(s, "-")
+    first, ok := weekdayNames[from]
+    if !ok {
+        return nil, fmt.Errorf("unknown weekday %q", from)
+    }
+    if !isRange {
+        return []time.Weekday{first}, nil
+    }
+    last, ok := weekdayNames[to]
+    if !ok {
+        return nil, fmt.Errorf("unknown weekday %q", to)
+    }
+
+    days := []time.Weekday{first}
+    for d := first; d != last; {
+        d = (d + 1) % 7
+        days = append(days, d)
+    }
+    return days, nil
+}
+
+// parseWorkHours reads entries such as {"mon-fri": "09:00-17:30",
+// "sat": "10:00-14:00", "sun": "off"}. Entries naming fewer days win over
+// wider ranges, and days that are not listed keep the whole day visible.
+func parseWorkHours(entries map[string]string) ([7]dayWindow, error) {
+    var hours [7]dayWindow
+    for i := range hours {
+        hours[i] = fullDay
+    }
+
+    type entry struct {
+        key    string
+        days   []time.Weekday
+        window dayWindow
+    }
+    var parsed []entry
+    for key, value := range entries {
+        days, err := parseWeekdays(strings.ToLower(strings.TrimSpace(key)))
+        if err != nil {
+            return hours, err
+        }
+        w, err := parseWindow(strings.ToLower(strings.TrimSpace(value)))
+        if err != nil {
+            return hours, fmt.Errorf("%s: %v", key, err)
+        }
+        parsed = append(parsed, entry{key: key, days: days, window: w})
+    }
+    sort.Slice(parsed, func(i, j int) bool {
+        if len(parsed[i].days) != len(parsed[j].days) {
+            return len(parsed[i].days) > len(parsed[j].days)
+        }
+        return parsed[i].key < parsed[j].key
+    })
+
+    for _, e := range parsed {
+        for _, d := range e.days {
+            hours[d] = e.window
+        }
+    }
+
+    return hours, nil
+}